@@ -0,0 +1,152 @@
+package warc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	fp "path/filepath"
+	"regexp"
+
+	"github.com/go-shiori/warc/internal/archiver"
+)
+
+var rxBlobDigest = regexp.MustCompile(`^sha256:([0-9a-f]{64})$`)
+
+// BlobStore is a content-addressed storage that shared between archives,
+// used to deduplicate resources that archived many times, e.g. fonts and
+// framework CSS. The content is keyed by its digest, which is "sha256:"
+// followed by hex encoded SHA-256 of the uncompressed resource.
+type BlobStore = archiver.BlobStore
+
+// DirBlobStore is BlobStore that saves each blob as file inside a directory.
+type DirBlobStore struct {
+	dir string
+}
+
+// NewDirBlobStore creates blob store inside the specified directory.
+func NewDirBlobStore(dir string) (*DirBlobStore, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob store: %v", err)
+	}
+
+	return &DirBlobStore{dir: dir}, nil
+}
+
+// Has checks if blob with specified digest exists.
+func (s *DirBlobStore) Has(digest string) (bool, error) {
+	path, err := s.path(digest)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Get returns the blob with specified digest.
+func (s *DirBlobStore) Get(digest string) ([]byte, error) {
+	path, err := s.path(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+// Put saves the blob with specified digest. The blob is written into
+// temporary file first, so the other readers never see partial blob.
+func (s *DirBlobStore) Put(digest string, content []byte) error {
+	path, err := s.path(digest)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(fp.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(fp.Dir(path), "tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpFile.Write(content)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// path returns the file path for the specified digest. The blobs are
+// grouped by the first two characters of their hash, so the directory
+// doesn't grow too big.
+func (s *DirBlobStore) path(digest string) (string, error) {
+	matches := rxBlobDigest.FindStringSubmatch(digest)
+	if matches == nil {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+
+	hash := matches[1]
+	return fp.Join(s.dir, hash[:2], hash), nil
+}
+
+// OpenWithBlobStore opens the archive from specified path, using the blob
+// store to resolve the resources that saved as reference to the store.
+func OpenWithBlobStore(path string, store BlobStore) (*Archive, error) {
+	arc, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	arc.blobStore = store
+	return arc, nil
+}
+
+// OpenStorageWithBlobStore opens the archive inside the specified storage,
+// using the blob store to resolve the resources that saved as reference.
+// Closing the archive will close the storage as well.
+func OpenStorageWithBlobStore(s Storage, store BlobStore) (*Archive, error) {
+	arc, err := OpenStorage(s)
+	if err != nil {
+		return nil, err
+	}
+
+	arc.blobStore = store
+	return arc, nil
+}
+
+// resourceContent returns the compressed content of resource inside the
+// record. If the content is saved as reference, it's read from blob store.
+func (arc *Archive) resourceContent(name string, record Record) ([]byte, error) {
+	if content := record["content"]; content != nil {
+		return content, nil
+	}
+
+	ref := record["ref"]
+	if ref == nil {
+		return nil, fmt.Errorf("%s doesn't exist", name)
+	}
+
+	if arc.blobStore == nil {
+		return nil, fmt.Errorf("%s is saved in blob store, which is not specified", name)
+	}
+
+	content, err := arc.blobStore.Get(string(ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from blob store: %v", name, err)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,113 @@
+package warc
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	nurl "net/url"
+	"strings"
+)
+
+// capture is a single HTTP response captured by other tools,
+// e.g. inside a WARC or MHTML file.
+type capture struct {
+	StatusCode int
+	Header     http.Header
+	Content    []byte
+}
+
+// captureTransport is HTTP transport that serves the request from
+// the captured responses instead of the internet. It's used to import
+// the captures from other tools by archiving them just like a page
+// that downloaded from the internet.
+type captureTransport struct {
+	captures  map[string]capture
+	aliases   map[string]string
+	rootURL   string
+	firstHTML string
+}
+
+// newCaptureTransport creates an empty capture transport.
+func newCaptureTransport() *captureTransport {
+	return &captureTransport{
+		captures: make(map[string]capture),
+		aliases:  make(map[string]string),
+	}
+}
+
+// addCapture saves the capture for the specified URL. If there are
+// several captures for the same URL, only the first one will be used.
+func (t *captureTransport) addCapture(url string, res capture) {
+	key := captureKey(url)
+	if key == "" {
+		return
+	}
+
+	if _, exist := t.captures[key]; exist {
+		return
+	}
+
+	t.captures[key] = res
+
+	if t.firstHTML == "" &&
+		res.StatusCode >= 200 && res.StatusCode < 300 &&
+		strings.Contains(res.Header.Get("Content-Type"), "text/html") {
+		t.firstHTML = url
+	}
+}
+
+// RoundTrip serves the request from the captured responses.
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := captureKey(req.URL.String())
+	for i := 0; i < 10; i++ {
+		alias, exist := t.aliases[key]
+		if !exist {
+			break
+		}
+		key = alias
+	}
+
+	res, exist := t.captures[key]
+	if !exist {
+		res = capture{
+			StatusCode: http.StatusNotFound,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Content:    []byte("resource is not captured"),
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)),
+		StatusCode:    res.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cloneHeader(res.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(res.Content)),
+		ContentLength: int64(len(res.Content)),
+		Request:       req,
+	}, nil
+}
+
+// captureKey normalizes URL so it can be matched with the URL
+// that requested by archiver, which already cleaned by processor.
+func captureKey(url string) string {
+	parsedURL, err := nurl.Parse(strings.TrimSpace(url))
+	if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return ""
+	}
+
+	if parsedURL.RawQuery != "" {
+		queries := parsedURL.Query()
+		for key := range queries {
+			if strings.HasPrefix(key, "utm_") {
+				queries.Del(key)
+			}
+		}
+		parsedURL.RawQuery = queries.Encode()
+	}
+
+	parsedURL.Fragment = ""
+	return strings.TrimRight(parsedURL.String(), "/")
+}
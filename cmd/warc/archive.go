@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	nurl "net/url"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/go-shiori/warc"
+)
+
+func archiveCmd(args []string) error {
+	flags := flag.NewFlagSet("archive", flag.ExitOnError)
+	output := flags.String("o", "", "path of the archive file (default: hostname of the URL)")
+	userAgent := flags.String("ua", "", "user agent that used when downloading the page")
+	quiet := flags.Bool("q", false, "don't print the archival log")
+	concurrency := flags.Int("j", warc.DefaultMaxConcurrency, "max number of concurrent downloads")
+	hostConcurrency := flags.Int("host-j", warc.DefaultMaxHostConcurrency, "max number of concurrent downloads per host")
+	hostDelay := flags.Duration("delay", 0, "min delay between requests to the same host")
+	maxAttempts := flags.Int("retry", warc.DefaultRetryPolicy.MaxAttempts, "max number of attempts for each download")
+	scripts := flags.String("js", "remove", "how to archive the scripts: remove, keep or sandbox")
+	recomputeSRI := flags.Bool("sri", false, "recompute the integrity attributes instead of removing them")
+	blobDir := flags.String("blobs", "", "directory of the blob store for deduplicating resources")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: warc archive [flags] <url>")
+		flags.PrintDefaults()
+	}
+	parseFlags(flags, args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// Prepare output path
+	url := flags.Arg(0)
+	dstPath := *output
+	if dstPath == "" {
+		parsedURL, err := nurl.ParseRequestURI(url)
+		if err != nil || parsedURL.Hostname() == "" {
+			return fmt.Errorf("url %q is not valid", url)
+		}
+		dstPath = strings.Replace(parsedURL.Hostname(), ":", "-", -1)
+	}
+
+	// Cancel the archival on interrupt
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	scriptPolicy, err := parseScriptPolicy(*scripts)
+	if err != nil {
+		return err
+	}
+
+	integrityPolicy := warc.IntegrityStrip
+	if *recomputeSRI {
+		integrityPolicy = warc.IntegrityRecompute
+	}
+
+	retryPolicy := warc.DefaultRetryPolicy
+	retryPolicy.MaxAttempts = *maxAttempts
+
+	var blobStore warc.BlobStore
+	if *blobDir != "" {
+		store, err := warc.NewDirBlobStore(*blobDir)
+		if err != nil {
+			return err
+		}
+		blobStore = store
+	}
+
+	req := warc.ArchivalRequest{
+		URL:                url,
+		UserAgent:          *userAgent,
+		LogEnabled:         !*quiet,
+		MaxConcurrency:     *concurrency,
+		MaxHostConcurrency: *hostConcurrency,
+		HostDelay:          *hostDelay,
+		RetryPolicy:        retryPolicy,
+		ScriptPolicy:       scriptPolicy,
+		IntegrityPolicy:    integrityPolicy,
+		BlobStore:          blobStore,
+	}
+
+	report, err := warc.NewArchiveContext(ctx, req, dstPath)
+	if err != nil {
+		return err
+	}
+
+	for _, res := range report.Failed() {
+		fmt.Fprintf(os.Stderr, "failed to archive %s: %v\n", res.URL, res.Err)
+	}
+
+	fmt.Printf("%d of %d resources archived\n", report.Succeeded(), len(report.Resources))
+	fmt.Println("archive saved to", dstPath)
+	return nil
+}
+
+// parseScriptPolicy parses the script policy from its name.
+func parseScriptPolicy(name string) (warc.ScriptPolicy, error) {
+	switch name {
+	case "remove":
+		return warc.ScriptRemove, nil
+	case "keep":
+		return warc.ScriptKeep, nil
+	case "sandbox":
+		return warc.ScriptSandbox, nil
+	default:
+		return 0, fmt.Errorf("unknown script policy %q", name)
+	}
+}
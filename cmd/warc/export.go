@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-shiori/warc"
+)
+
+func exportCmd(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "html", "output format: html, mhtml, warc or zip")
+	output := flags.String("o", "", "path of the output file (default: stdout)")
+	blobDir := blobsFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: warc export [flags] <archive>")
+		flags.PrintDefaults()
+	}
+	parseFlags(flags, args)
+
+	// Pick the exporter
+	var exporter func(*warc.Archive, io.Writer) error
+	switch *format {
+	case "html":
+		exporter = warc.ExportHTML
+	case "mhtml":
+		exporter = warc.ExportMHTML
+	case "warc":
+		exporter = warc.ExportWARC
+	case "zip":
+		exporter = warc.ExportZip
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	arc, err := openArchive(flags, *blobDir)
+	if err != nil {
+		return err
+	}
+	defer arc.Close()
+
+	// Prepare output
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return exporter(arc, w)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-shiori/warc"
+)
+
+func lsCmd(args []string) error {
+	flags := flag.NewFlagSet("ls", flag.ExitOnError)
+	long := flags.Bool("l", false, "print the type, size, status and URL of each resource")
+	blobDir := blobsFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: warc ls [flags] <archive>")
+		flags.PrintDefaults()
+	}
+	parseFlags(flags, args)
+
+	arc, err := openArchive(flags, *blobDir)
+	if err != nil {
+		return err
+	}
+	defer arc.Close()
+
+	if !*long {
+		return arc.Walk(func(res warc.ResourceInfo) error {
+			_, err := fmt.Println(res.Name)
+			return err
+		})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tSIZE\tCOMPRESSED\tSTATUS\tURL")
+	err = arc.Walk(func(res warc.ResourceInfo) error {
+		status := "-"
+		if res.StatusCode != 0 {
+			status = fmt.Sprint(res.StatusCode)
+		}
+
+		_, err := fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", res.Name,
+			res.ContentType, res.Size, res.CompressedSize, status, res.URL)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func catCmd(args []string) error {
+	flags := flag.NewFlagSet("cat", flag.ExitOnError)
+	blobDir := blobsFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: warc cat [flags] <archive> [resource]")
+		fmt.Fprintln(os.Stderr, "If resource is not specified, the archive root will be printed.")
+		flags.PrintDefaults()
+	}
+	parseFlags(flags, args)
+
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	arc, err := openArchiveFile(flags.Arg(0), *blobDir)
+	if err != nil {
+		return err
+	}
+	defer arc.Close()
+
+	reader, err := arc.Open(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(os.Stdout, reader)
+	return err
+}
+
+func infoCmd(args []string) error {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	blobDir := blobsFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: warc info [flags] <archive>")
+		flags.PrintDefaults()
+	}
+	parseFlags(flags, args)
+
+	arc, err := openArchive(flags, *blobDir)
+	if err != nil {
+		return err
+	}
+	defer arc.Close()
+
+	// Use manifest if available, otherwise use the root's metadata
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	manifest, err := arc.Manifest()
+	if err == nil {
+		fmt.Fprintf(w, "URL:\t%s\n", manifest.URL)
+		if manifest.FinalURL != "" && manifest.FinalURL != manifest.URL {
+			fmt.Fprintf(w, "Final URL:\t%s\n", manifest.FinalURL)
+		}
+		fmt.Fprintf(w, "Title:\t%s\n", manifest.Title)
+		if !manifest.StartedAt.IsZero() {
+			fmt.Fprintf(w, "Started at:\t%s\n", manifest.StartedAt.Format(time.RFC1123))
+			fmt.Fprintf(w, "Finished at:\t%s\n", manifest.FinishedAt.Format(time.RFC1123))
+		}
+		fmt.Fprintf(w, "User agent:\t%s\n", manifest.UserAgent)
+		fmt.Fprintf(w, "Version:\t%s\n", manifest.Version)
+	} else {
+		fmt.Fprintf(w, "Warning:\tlegacy archive, run \"warc migrate\" to upgrade it\n")
+
+		root, err := arc.Stat("")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "URL:\t%s\n", root.URL)
+		if root.FinalURL != "" && root.FinalURL != root.URL {
+			fmt.Fprintf(w, "Final URL:\t%s\n", root.FinalURL)
+		}
+		if !root.FetchedAt.IsZero() {
+			fmt.Fprintf(w, "Fetched at:\t%s\n", root.FetchedAt.Format(time.RFC1123))
+		}
+	}
+
+	resources, err := arc.List()
+	if err != nil {
+		return err
+	}
+
+	var totalSize, totalCompressed int64
+	for _, res := range resources {
+		totalSize += res.Size
+		totalCompressed += res.CompressedSize
+	}
+
+	fmt.Fprintf(w, "Format version:\t%d\n", arc.FormatVersion())
+	fmt.Fprintf(w, "Resources:\t%d\n", len(resources))
+	fmt.Fprintf(w, "Total size:\t%d bytes (%d bytes compressed)\n", totalSize, totalCompressed)
+
+	for _, failure := range manifest.Failures {
+		if failure.StatusCode != 0 {
+			fmt.Fprintf(w, "Skipped:\t%s (status %d)\n", failure.URL, failure.StatusCode)
+		} else {
+			fmt.Fprintf(w, "Failed:\t%s (%d attempts): %s\n", failure.URL, len(failure.Attempts), failure.Error)
+		}
+	}
+
+	return w.Flush()
+}
+
+// blobsFlag defines the flag for the blob store used by the archive.
+func blobsFlag(flags *flag.FlagSet) *string {
+	return flags.String("blobs", "", "directory of the blob store that used by the archive")
+}
+
+// openArchive opens the archive specified as the only argument.
+func openArchive(flags *flag.FlagSet, blobDir string) (*warc.Archive, error) {
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	return openArchiveFile(flags.Arg(0), blobDir)
+}
+
+// openArchiveFile opens the archive in path. If blobDir is specified, it's
+// used as blob store for the resources that saved as reference.
+func openArchiveFile(path, blobDir string) (*warc.Archive, error) {
+	if blobDir == "" {
+		return warc.Open(path)
+	}
+
+	store, err := warc.NewDirBlobStore(blobDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return warc.OpenWithBlobStore(path, store)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `warc is a tool for archiving web page and inspecting the archive.
+
+Usage:
+  warc <command> [flags] [arguments]
+
+Commands:
+  archive   archive a web page from URL
+  ls        list resources inside archive
+  cat       print content of a resource inside archive
+  info      print information of archive
+  serve     serve archive through HTTP
+  export    export archive as HTML, MHTML, WARC or zip file
+  migrate   upgrade archive to the newest format version
+
+Run "warc <command> -h" for more information about a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	cmd, args := os.Args[1], os.Args[2:]
+
+	switch cmd {
+	case "archive":
+		err = archiveCmd(args)
+	case "ls":
+		err = lsCmd(args)
+	case "cat":
+		err = catCmd(args)
+	case "info":
+		err = infoCmd(args)
+	case "serve":
+		err = serveCmd(args)
+	case "export":
+		err = exportCmd(args)
+	case "migrate":
+		err = migrateCmd(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses the flags, which may be placed either before or after
+// the positional arguments, e.g. "warc archive <url> -o file". Everything
+// after "--" is treated as positional argument.
+func parseFlags(flags *flag.FlagSet, args []string) {
+	positionals := []string{}
+	for {
+		flags.Parse(args)
+		rest := flags.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positionals = append(positionals, rest...)
+			break
+		}
+
+		if len(rest) == 0 {
+			break
+		}
+
+		positionals = append(positionals, rest[0])
+		args = rest[1:]
+	}
+
+	flags.Parse(append([]string{"--"}, positionals...))
+}
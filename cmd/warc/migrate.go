@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-shiori/warc"
+)
+
+func migrateCmd(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: warc migrate <archive> <new-archive>")
+	}
+	parseFlags(flags, args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	err := warc.Migrate(flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("archive migrated to format version %d in %s\n", warc.FormatVersion, flags.Arg(1))
+	return nil
+}
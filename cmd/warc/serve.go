@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-shiori/warc"
+)
+
+func serveCmd(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	blobDir := blobsFlag(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: warc serve [flags] <archive>")
+		flags.PrintDefaults()
+	}
+	parseFlags(flags, args)
+
+	arc, err := openArchive(flags, *blobDir)
+	if err != nil {
+		return err
+	}
+	defer arc.Close()
+
+	fmt.Printf("serving %s on %s\n", flags.Arg(0), *addr)
+	return http.ListenAndServe(*addr, warc.Handler(arc))
+}
@@ -0,0 +1,31 @@
+package warc
+
+import (
+	"github.com/go-shiori/warc/internal/archiver"
+)
+
+// EventType is the type of archival event.
+type EventType = archiver.EventType
+
+// Event is the progress of the archival. Attempt is the number of the
+// download attempt, starting from 1. For BytesReceived, Bytes is the
+// number of bytes received so far and TotalBytes is the expected size,
+// or -1 if it's unknown. For ResourceSaved, Bytes is the saved size.
+type Event = archiver.Event
+
+const (
+	// ResourceQueued is sent when a resource is about to be archived.
+	ResourceQueued = archiver.ResourceQueued
+
+	// DownloadStarted is sent each time a download attempt is started.
+	DownloadStarted = archiver.DownloadStarted
+
+	// BytesReceived is sent while the response body is being received.
+	BytesReceived = archiver.BytesReceived
+
+	// ResourceSaved is sent once the resource is saved into storage.
+	ResourceSaved = archiver.ResourceSaved
+
+	// ResourceFailed is sent when the resource failed to be archived.
+	ResourceFailed = archiver.ResourceFailed
+)
@@ -19,8 +19,14 @@ func main() {
 	}
 
 	// Start archival
-	err := warc.NewArchive(req, "ap-news")
+	report, err := warc.NewArchive(req, "ap-news")
 	if err != nil {
 		log.Fatalln(err)
 	}
+
+	// Show the resources that failed to be archived
+	log.Printf("%d of %d resources archived\n", report.Succeeded(), len(report.Resources))
+	for _, res := range report.Failed() {
+		log.Printf("failed to archive %s: %v\n", res.URL, res.Err)
+	}
 }
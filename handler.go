@@ -0,0 +1,134 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-shiori/warc/internal/archiver"
+)
+
+var errResourceNotFound = errors.New("resource not found")
+
+// archiveHandler is HTTP handler that serves resources inside archive.
+type archiveHandler struct {
+	arc *Archive
+}
+
+// Handler returns an HTTP handler that serves the resources inside archive.
+// The archive root is served at the directory path, e.g. "/" or "/prefix/",
+// while the other resources are served by their name. Since resource name
+// never contains slash, the handler only uses the last segment of the path,
+// so it can be mounted under any prefix, with or without http.StripPrefix.
+//
+// If the client accepts gzip encoding, the resource is served as it is
+// stored in the archive. Otherwise it will be decompressed first. The
+// handler also supports HEAD, conditional and range requests.
+func Handler(arc *Archive) http.Handler {
+	return &archiveHandler{arc: arc}
+}
+
+// ServeHTTP serves the requested resource.
+func (h *archiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET and HEAD
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Resolve resource name from the last segment of the path
+	name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if name == "" {
+		name = "archive-root"
+	}
+
+	// Serve the resource
+	err := h.serveResource(w, r, name)
+	switch {
+	case err == errResourceNotFound:
+		http.NotFound(w, r)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveResource serves the resource with specified name.
+func (h *archiveHandler) serveResource(w http.ResponseWriter, r *http.Request, name string) error {
+	if name == archiver.ManifestBucket {
+		return errResourceNotFound
+	}
+
+	record, err := h.arc.storage.GetResource(name)
+	if err == ErrNotExist || err == nil && record["type"] == nil {
+		return errResourceNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", name, err)
+	}
+
+	content, err := h.arc.resourceContent(name, record)
+	if err != nil {
+		return err
+	}
+
+	info := resourceInfo(name, record)
+	sum := sha1.Sum(content)
+	etag := hex.EncodeToString(sum[:])
+
+	header := w.Header()
+	header.Set("Content-Type", info.ContentType)
+	header.Add("Vary", "Accept-Encoding")
+
+	if acceptsGzip(r) {
+		header.Set("Content-Encoding", "gzip")
+		header.Set("ETag", `"`+etag+`-gzip"`)
+		http.ServeContent(w, r, name, info.FetchedAt, bytes.NewReader(content))
+		return nil
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %v", name, err)
+	}
+
+	decompressed, err := ioutil.ReadAll(gzipReader)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %v", name, err)
+	}
+
+	header.Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, r, name, info.FetchedAt, bytes.NewReader(decompressed))
+	return nil
+}
+
+// acceptsGzip checks if the client accepts gzip encoding.
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(encoding, ";")
+		name := strings.TrimSpace(parts[0])
+		if name != "gzip" && name != "*" {
+			continue
+		}
+
+		// Make sure it's not explicitly refused with q=0
+		refused := false
+		for _, param := range parts[1:] {
+			param = strings.Replace(param, " ", "", -1)
+			if param == "q=0" || strings.HasPrefix(param, "q=0.") && strings.Trim(param[4:], "0") == "" {
+				refused = true
+			}
+		}
+
+		return !refused
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package warc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/go-shiori/warc/internal/processor"
+)
+
+// htmlExporter converts archived resources into data URIs.
+type htmlExporter struct {
+	arc      *Archive
+	cache    map[string]string
+	visiting map[string]struct{}
+}
+
+// ExportHTML writes the archive into w as a single self-contained HTML
+// file, which can be opened offline without any other files. Every resource
+// used by the archive root is inlined as data URI, while stylesheets from
+// <link> are inlined as <style>. The archived CSS and iframes are processed
+// recursively, so their resources are inlined as well.
+func ExportHTML(arc *Archive, w io.Writer) error {
+	exporter := &htmlExporter{
+		arc:      arc,
+		cache:    make(map[string]string),
+		visiting: make(map[string]struct{}),
+	}
+
+	content, _, err := arc.readContent("archive-root")
+	if err != nil {
+		return err
+	}
+
+	exporter.visiting["archive-root"] = struct{}{}
+	result, err := exporter.inlineHTML(content)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, result)
+	return err
+}
+
+// inlineHTML inlines all resources inside the HTML content.
+func (e *htmlExporter) inlineHTML(content []byte) (string, error) {
+	result, err := processor.InlineStylesheets(bytes.NewReader(content), e.stylesheet)
+	if err != nil {
+		return "", err
+	}
+
+	return processor.RewriteHTMLFile(strings.NewReader(result), e.dataURI)
+}
+
+// stylesheet returns the CSS rules of the resource with specified name.
+// The resources inside the rules will be inlined later with the HTML.
+func (e *htmlExporter) stylesheet(name string) string {
+	content, contentType, err := e.arc.readContent(name)
+	if err != nil || !strings.Contains(contentType, "text/css") {
+		return ""
+	}
+
+	return string(content)
+}
+
+// dataURI returns the data URI of the resource with specified name.
+// Returns empty string if the resource doesn't exist in archive, or
+// if it's referenced recursively by itself.
+func (e *htmlExporter) dataURI(name string) string {
+	if uri, cached := e.cache[name]; cached {
+		return uri
+	}
+
+	if _, visiting := e.visiting[name]; visiting {
+		return ""
+	}
+
+	content, contentType, err := e.arc.readContent(name)
+	if err != nil {
+		return ""
+	}
+
+	// Inline the resources inside HTML and CSS as well
+	e.visiting[name] = struct{}{}
+	defer delete(e.visiting, name)
+
+	switch {
+	case strings.Contains(contentType, "text/html"):
+		result, err := e.inlineHTML(content)
+		if err != nil {
+			return ""
+		}
+		content = []byte(result)
+	case strings.Contains(contentType, "text/css"):
+		content = []byte(processor.RewriteCSSFile(bytes.NewReader(content), e.dataURI))
+	}
+
+	mediaType := strings.Replace(contentType, " ", "", -1)
+	uri := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(content)
+	e.cache[name] = uri
+
+	return uri
+}
+
+// readContent reads the decompressed content of the specified resource.
+func (arc *Archive) readContent(name string) ([]byte, string, error) {
+	reader, err := arc.Open(name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %v", name, err)
+	}
+
+	return content, reader.ContentType, nil
+}
@@ -3,170 +3,478 @@ package archiver
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-shiori/warc/internal/processor"
+	"github.com/go-shiori/warc/internal/storage"
 	"github.com/sirupsen/logrus"
-	"go.etcd.io/bbolt"
 )
 
 // Request is struct that contains page data that want to be archived.
+// DeclaredType is the content type expected by the referrer, which is
+// used when the content type can't be decided from the server response.
 type Request struct {
-	Reader      io.Reader
-	URL         string
-	ContentType string
+	Reader       io.Reader
+	URL          string
+	ContentType  string
+	DeclaredType string
+	Referrer     string
 }
 
 // Archiver is struct that do the archival.
 type Archiver struct {
 	sync.RWMutex
 
-	DB         *bbolt.DB
-	UserAgent  string
-	LogEnabled bool
+	Storage            storage.Storage
+	UserAgent          string
+	LogEnabled         bool
+	HTTPClient         *http.Client
+	Transport          http.RoundTripper
+	BlobStore          BlobStore
+	MaxConcurrency     int
+	MaxHostConcurrency int
+	HostDelay          time.Duration
+	RetryPolicy        RetryPolicy
+	AcceptStatus       func(statusCode int) bool
+	OnEvent            func(Event)
+	ScriptPolicy       processor.ScriptPolicy
+	IntegrityPolicy    processor.IntegrityPolicy
+
+	// Replay is set when the responses are replayed from captures, in
+	// which case Retry-After is ignored since they will never change.
+	Replay bool
 
 	resourceMap map[string]struct{}
+	nameMap     map[string]struct{}
+	manifest    manifest
+	limiter     *limiter
+	report      Report
 }
 
-// Start starts the archival process
-func (arc *Archiver) Start(req Request) error {
+// Start starts the archival process. Once the context is cancelled,
+// the in-flight downloads are aborted and no new sub resources will
+// be archived, in which case the context's error will be returned.
+func (arc *Archiver) Start(ctx context.Context, req Request) error {
 	if arc.resourceMap == nil {
 		arc.resourceMap = make(map[string]struct{})
 	}
 
-	return arc.archive(req, true)
+	if arc.nameMap == nil {
+		arc.nameMap = make(map[string]struct{})
+	}
+
+	if arc.HTTPClient == nil {
+		arc.HTTPClient = newHTTPClient(arc.Transport)
+	}
+
+	if arc.RetryPolicy.MaxAttempts <= 0 {
+		arc.RetryPolicy = DefaultRetryPolicy()
+	}
+
+	if arc.AcceptStatus == nil {
+		arc.AcceptStatus = DefaultAcceptStatus
+	}
+
+	if arc.limiter == nil {
+		arc.limiter = newLimiter(arc.MaxConcurrency, arc.MaxHostConcurrency, arc.HostDelay)
+	}
+
+	arc.manifest.URL = req.URL
+	arc.manifest.StartedAt = time.Now()
+	arc.report.URL = req.URL
+	arc.report.StartedAt = arc.manifest.StartedAt
+
+	err := arc.archive(ctx, req, true)
+	if err == nil && ctx.Err() == nil && arc.IntegrityPolicy == processor.IntegrityRecompute {
+		arc.recomputeAllIntegrity()
+	}
+
+	arc.report.FinishedAt = time.Now()
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	if err != nil {
+		if _, isRootError := err.(*RootError); !isRootError {
+			err = &RootError{URL: req.URL, Err: err}
+		}
+		return err
+	}
+
+	return arc.saveManifest()
 }
 
-func (arc *Archiver) archive(req Request, root bool) error {
-	// Check if this request already processed before
-	arc.RLock()
+func (arc *Archiver) archive(ctx context.Context, req Request, root bool) error {
+	// Stop if archival is already cancelled
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Check if this request already processed before,
+	// otherwise mark it so it's only processed once
+	arc.Lock()
 	_, processed := arc.resourceMap[req.URL]
-	arc.RUnlock()
+	arc.resourceMap[req.URL] = struct{}{}
+	arc.Unlock()
 
 	if processed {
 		return nil
 	}
 
+	// Archive the resource and report the result
+	arc.emit(Event{Type: ResourceQueued, URL: req.URL, Referrer: req.Referrer})
+	report := ResourceReport{
+		URL:      req.URL,
+		Referrer: req.Referrer,
+	}
+
+	startTime := time.Now()
+	subResources, err := arc.archiveResource(ctx, req, root, &report)
+	report.Duration = time.Since(startTime)
+	report.Err = err
+	arc.addReport(report)
+
+	if err != nil {
+		arc.emit(Event{Type: ResourceFailed, URL: req.URL, Referrer: req.Referrer, Err: err})
+		return err
+	}
+
+	arc.emit(Event{
+		Type:     ResourceSaved,
+		URL:      req.URL,
+		Name:     report.Name,
+		Referrer: req.Referrer,
+		Bytes:    report.Size,
+	})
+
+	// Archive the sub resources. The number of concurrent downloads
+	// is limited by the limiter, so here all of them can be started.
+	wg := sync.WaitGroup{}
+	wg.Add(len(subResources))
+
+	for _, subResource := range subResources {
+		if ctx.Err() != nil {
+			wg.Done()
+			continue
+		}
+
+		go func(subResource processor.Resource) {
+			// Make sure to finish the WG
+			defer wg.Done()
+
+			// Archive the sub resource
+			var subResContent io.Reader
+			if len(subResource.Content) > 0 {
+				subResContent = bytes.NewBuffer(subResource.Content)
+			}
+
+			subResRequest := Request{
+				Reader:       subResContent,
+				URL:          subResource.URL,
+				DeclaredType: subResource.DeclaredType,
+				Referrer:     req.URL,
+			}
+
+			err := arc.archive(ctx, subResRequest, false)
+			if err != nil && ctx.Err() == nil {
+				arc.logWarning("Failed to save %s: %v\n", subResource.URL, err)
+			}
+		}(subResource)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// archiveResource downloads, processes and saves a single resource,
+// then returns its sub resources. The result is written into report.
+func (arc *Archiver) archiveResource(ctx context.Context, req Request, root bool, report *ResourceReport) ([]processor.Resource, error) {
 	// Download page if needed
-	if req.Reader == nil || req.ContentType == "" {
+	var err error
+	var resp *http.Response
+	var attempts []Attempt
+	fetchTime := time.Now()
+
+	if req.Reader == nil {
 		arc.logInfo("Downloading %s\n", req.URL)
 
-		resp, err := arc.downloadPage(req.URL)
+		resp, attempts, err = arc.downloadPage(ctx, req)
+		report.Attempts = attempts
 		if err != nil {
-			return fmt.Errorf("failed to download %s: %v", req.URL, err)
+			err = fmt.Errorf("failed to download %s: %v", req.URL, err)
+			if ctx.Err() == nil {
+				arc.addFailure(req.URL, 0, err, attempts)
+			}
+			return nil, err
 		}
 		defer resp.Body.Close()
 
+		report.StatusCode = resp.StatusCode
+
+		// Make sure the response is acceptable, so the error
+		// page is not saved in place of the actual resource
+		if !arc.AcceptStatus(resp.StatusCode) {
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+			arc.addFailure(req.URL, resp.StatusCode, err, attempts)
+			if root {
+				return nil, &RootError{URL: req.URL, StatusCode: resp.StatusCode, Err: err}
+			}
+			return nil, err
+		}
+
 		req.Reader = resp.Body
 		req.ContentType = resp.Header.Get("Content-Type")
 	}
 
+	// Resolve the actual content type, since the server might send
+	// a generic one, and the embedded content doesn't have any
+	content, err := ioutil.ReadAll(req.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", req.URL, err)
+	}
+
+	req.ContentType = sniffContentType(req.ContentType, req.DeclaredType, req.URL, content)
+	req.Reader = bytes.NewReader(content)
+
 	// Process input
-	var err error
 	resource := processor.Resource{}
 	subResources := []processor.Resource{}
 	processorRequest := processor.Request{
-		Reader: req.Reader,
-		URL:    req.URL,
+		Reader:          req.Reader,
+		URL:             req.URL,
+		ScriptPolicy:    arc.ScriptPolicy,
+		IntegrityPolicy: arc.IntegrityPolicy,
 	}
 
 	switch {
 	case strings.Contains(req.ContentType, "text/html"):
+		report.Processor = "html"
 		resource, subResources, err = processor.ProcessHTMLFile(processorRequest)
 		if !root && !resource.IsEmbed {
 			subResources = []processor.Resource{}
 		}
 	case strings.Contains(req.ContentType, "text/css") && !root:
+		report.Processor = "css"
 		resource, subResources, err = processor.ProcessCSSFile(processorRequest)
+	case processor.IsJSContentType(req.ContentType) && !root && arc.ScriptPolicy != processor.ScriptRemove:
+		report.Processor = "js"
+		resource, subResources, err = processor.ProcessJSFile(processorRequest)
 	default:
+		report.Processor = "general"
 		resource, err = processor.ProcessGeneralFile(processorRequest)
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to archive %s: %v", req.URL, err)
+		return nil, fmt.Errorf("failed to archive %s: %v", req.URL, err)
 	}
 
 	// Save resource to storage
 	if root {
 		resource.Name = "archive-root"
+		arc.manifest.Title = resource.Title
+		if resp != nil && resp.Request != nil {
+			arc.manifest.FinalURL = resp.Request.URL.String()
+		}
 	}
 
-	err = arc.saveResource(resource, req.ContentType)
+	report.Name = resource.Name
+	report.Size = int64(len(resource.Content))
+
+	err = arc.saveResource(resource, req.ContentType, resp, fetchTime, attempts)
 	if err != nil {
-		return fmt.Errorf("failed to save %s: %v", req.URL, err)
+		return nil, fmt.Errorf("failed to save %s: %v", req.URL, err)
 	}
 
-	// Save this resource to map
-	arc.Lock()
-	arc.resourceMap[req.URL] = struct{}{}
-	arc.Unlock()
-
 	arc.logInfo("Saved %s (%d)\n", resource.URL, len(resource.Content))
+	return subResources, nil
+}
 
-	// Archive the sub resources
-	wg := sync.WaitGroup{}
-	wg.Add(len(subResources))
+// downloadPage downloads data from the specified URL. The whole body is
+// read before returning, so the download slot can be released as soon
+// as possible. The failed download is retried following the retry policy,
+// and if the server asks to slow down using Retry-After, the next requests
+// to the same host are postponed. Returns the history of all attempts.
+func (arc *Archiver) downloadPage(ctx context.Context, arcReq Request) (*http.Response, []Attempt, error) {
+	url := arcReq.URL
+	policy := arc.RetryPolicy
+	attempts := []Attempt{}
+
+	for {
+		// Prepare request
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, attempts, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("User-Agent", arc.UserAgent)
 
-	semaphore := make(chan struct{}, 5)
-	defer close(semaphore)
+		// Wait for our turn, then send request
+		host := req.URL.Host
+		slot, err := arc.limiter.acquire(ctx, host)
+		if err != nil {
+			return nil, attempts, err
+		}
 
-	for _, subResource := range subResources {
-		go func(subResource processor.Resource) {
-			// Make sure to finish the WG
-			defer wg.Done()
+		event := Event{
+			Type:     DownloadStarted,
+			URL:      url,
+			Referrer: arcReq.Referrer,
+			Attempt:  len(attempts) + 1,
+		}
+		arc.emit(event)
+
+		attempt := Attempt{StartedAt: time.Now()}
+		resp, err := arc.HTTPClient.Do(req)
+		if err == nil {
+			event.Type = BytesReceived
+			event.TotalBytes = resp.ContentLength
+
+			var body []byte
+			body, err = ioutil.ReadAll(&progressReader{Reader: resp.Body, arc: arc, event: event})
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
 
-			// Register goroutine to semaphore
-			semaphore <- struct{}{}
-			defer func() {
-				<-semaphore
-			}()
+		arc.limiter.release(slot)
+		attempt.Duration = time.Since(attempt.StartedAt)
 
-			// Archive the sub resource
-			var subResContent io.Reader
-			if len(subResource.Content) > 0 {
-				subResContent = bytes.NewBuffer(subResource.Content)
+		if err != nil {
+			attempt.Error = err.Error()
+		} else {
+			attempt.StatusCode = resp.StatusCode
+		}
+		attempts = append(attempts, attempt)
+
+		// If server asks to slow down, postpone the next requests to this
+		// host. If it asks for too long, the download is not retried and
+		// the delay is capped, so the other resources are not held up.
+		var wait time.Duration
+		if resp != nil && !arc.Replay {
+			if d, ok := retryAfter(resp); ok {
+				wait = d
+				if d > maxRetryAfter {
+					d = maxRetryAfter
+				}
+				arc.limiter.delay(host, d)
 			}
+		}
 
-			subResRequest := Request{
-				Reader: subResContent,
-				URL:    subResource.URL,
-			}
+		// Check if the download should be retried
+		retry := false
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			retry = policy.retryError(err)
+		default:
+			retry = policy.retryStatus(resp.StatusCode)
+		}
 
-			err := arc.archive(subResRequest, false)
+		if !retry || len(attempts) >= policy.MaxAttempts || wait > maxRetryAfter {
 			if err != nil {
-				arc.logWarning("Failed to save %s: %v\n", subResource.URL, err)
+				return nil, attempts, err
 			}
-		}(subResource)
+			return resp, attempts, nil
+		}
+
+		backoff := policy.backoff(len(attempts))
+		arc.logInfo("Retrying %s in %v\n", url, backoff)
+		if err := sleep(ctx, backoff); err != nil {
+			return nil, attempts, err
+		}
 	}
+}
 
-	wg.Wait()
+func (arc *Archiver) saveResource(resource processor.Resource, contentType string, resp *http.Response, fetchTime time.Time, attempts []Attempt) error {
+	// Prepare the values that will be saved
+	values := storage.Record{
+		"type": []byte(contentType),
+		"url":  []byte(resource.URL),
+		"time": []byte(fetchTime.UTC().Format(time.RFC3339Nano)),
+	}
 
-	return nil
-}
+	err := arc.setContent(values, resource.Content)
+	if err != nil {
+		return err
+	}
+
+	// If resource is downloaded, save its HTTP metadata as well
+	if resp != nil {
+		values["status"] = []byte(resp.Proto + " " + resp.Status)
+		values["header"] = encodeHeader(resp.Header)
+		values["original-type"] = []byte(resp.Header.Get("Content-Type"))
+
+		if resp.Request != nil {
+			values["final-url"] = []byte(resp.Request.URL.String())
+			values["request-header"] = encodeHeader(resp.Request.Header)
+		}
+	}
+
+	if len(attempts) > 0 {
+		values["attempts"], err = json.Marshal(attempts)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Different URLs might have the same resource name, so claim the
+	// name first to make sure only one of them is saved. If resource
+	// with the same name already saved, keep the old one.
+	arc.Lock()
+	_, claimed := arc.nameMap[resource.Name]
+	arc.nameMap[resource.Name] = struct{}{}
+	arc.Unlock()
+
+	if claimed {
+		return nil
+	}
+
+	_, err = arc.Storage.StatResource(resource.Name)
+	if err == nil {
+		return nil
+	}
+
+	if err == storage.ErrNotExist {
+		err = arc.Storage.PutResource(resource.Name, values)
+	}
 
-// DownloadData downloads data from the specified URL.
-func (arc *Archiver) downloadPage(url string) (*http.Response, error) {
-	// Prepare request
-	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		arc.Lock()
+		delete(arc.nameMap, resource.Name)
+		arc.Unlock()
+		return err
 	}
 
-	// Send request
-	req.Header.Set("User-Agent", arc.UserAgent)
-	return httpClient.Do(req)
+	// Update statistic for manifest
+	arc.Lock()
+	arc.manifest.ResourceCount++
+	arc.manifest.TotalSize += int64(len(resource.Content))
+	arc.Unlock()
+
+	return nil
 }
 
-func (arc *Archiver) saveResource(resource processor.Resource, contentType string) error {
+// setContent compresses the content then puts it into the record, along
+// with its sizes and digest. If the same content already exists in blob
+// store, the reference is saved instead of the content. Otherwise, it's
+// put there for the next archives.
+func (arc *Archiver) setContent(values storage.Record, content []byte) error {
 	// Compress content
 	buffer := bytes.NewBuffer(nil)
 	gzipper := gzip.NewWriter(buffer)
 
-	_, err := gzipper.Write(resource.Content)
+	_, err := gzipper.Write(content)
 	if err != nil {
 		return fmt.Errorf("compress failed: %v", err)
 	}
@@ -176,31 +484,51 @@ func (arc *Archiver) saveResource(resource processor.Resource, contentType strin
 		return fmt.Errorf("compress failed: %v", err)
 	}
 
-	err = arc.DB.Batch(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte(resource.Name))
-		if bucket != nil {
-			return nil
-		}
+	sum := sha256.Sum256(content)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
 
-		bucket, err := tx.CreateBucketIfNotExists([]byte(resource.Name))
-		if err != nil {
-			return err
-		}
+	delete(values, "ref")
+	values["content"] = buffer.Bytes()
+	values["size"] = []byte(strconv.Itoa(len(content)))
+	values["compressed-size"] = []byte(strconv.Itoa(buffer.Len()))
+	values["digest"] = []byte(digest)
 
-		err = bucket.Put([]byte("content"), buffer.Bytes())
+	if arc.BlobStore != nil {
+		exist, err := arc.BlobStore.Has(digest)
 		if err != nil {
-			return err
+			return fmt.Errorf("blob store failed: %v", err)
 		}
 
-		err = bucket.Put([]byte("type"), []byte(contentType))
-		if err != nil {
-			return err
+		if exist {
+			delete(values, "content")
+			values["ref"] = []byte(digest)
+		} else if err = arc.BlobStore.Put(digest, buffer.Bytes()); err != nil {
+			return fmt.Errorf("blob store failed: %v", err)
 		}
+	}
 
-		return nil
+	return nil
+}
+
+// addFailure records the resource that failed to be archived. The status
+// code is zero if the resource is failed before the response is received.
+func (arc *Archiver) addFailure(url string, statusCode int, err error, attempts []Attempt) {
+	arc.Lock()
+	defer arc.Unlock()
+
+	arc.manifest.Failures = append(arc.manifest.Failures, Failure{
+		URL:        url,
+		StatusCode: statusCode,
+		Error:      err.Error(),
+		Attempts:   attempts,
 	})
+}
 
-	return err
+// encodeHeader encodes HTTP header in its wire format.
+func encodeHeader(header http.Header) []byte {
+	buffer := bytes.NewBuffer(nil)
+	header.Write(buffer)
+	return buffer.Bytes()
 }
 
 func (arc *Archiver) logInfo(format string, args ...interface{}) {
@@ -0,0 +1,11 @@
+package archiver
+
+// BlobStore is a content-addressed storage that shared between archives.
+// The content is keyed by its digest, i.e. "sha256:" followed by the hex
+// encoded SHA-256 of the uncompressed resource, while the stored value is
+// the compressed content as it would be saved in archive.
+type BlobStore interface {
+	Has(digest string) (bool, error)
+	Get(digest string) ([]byte, error)
+	Put(digest string, content []byte) error
+}
@@ -0,0 +1,81 @@
+package archiver
+
+import (
+	"io"
+)
+
+// EventType is the type of archival event.
+type EventType int
+
+const (
+	// ResourceQueued is sent when a resource is about to be archived.
+	ResourceQueued EventType = iota
+
+	// DownloadStarted is sent each time a download attempt is started.
+	DownloadStarted
+
+	// BytesReceived is sent while the response body is being received.
+	BytesReceived
+
+	// ResourceSaved is sent once the resource is saved into storage.
+	ResourceSaved
+
+	// ResourceFailed is sent when the resource failed to be archived.
+	ResourceFailed
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case ResourceQueued:
+		return "ResourceQueued"
+	case DownloadStarted:
+		return "DownloadStarted"
+	case BytesReceived:
+		return "BytesReceived"
+	case ResourceSaved:
+		return "ResourceSaved"
+	case ResourceFailed:
+		return "ResourceFailed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event is the progress of the archival.
+type Event struct {
+	Type       EventType
+	URL        string
+	Name       string
+	Referrer   string
+	Attempt    int
+	Bytes      int64
+	TotalBytes int64
+	Err        error
+}
+
+// emit sends the event to the event handler, if any.
+func (arc *Archiver) emit(event Event) {
+	if arc.OnEvent != nil {
+		arc.OnEvent(event)
+	}
+}
+
+// progressReader is reader that sends BytesReceived
+// event each time some bytes is read.
+type progressReader struct {
+	io.Reader
+	arc   *Archiver
+	event Event
+}
+
+// Read reads the data then reports the progress.
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.event.Bytes += int64(n)
+		r.arc.emit(r.event)
+	}
+
+	return n, err
+}
@@ -1,23 +1,20 @@
 package archiver
 
 import (
-	"crypto/tls"
 	"net/http"
 	"net/http/cookiejar"
 	"time"
 )
 
-var httpClient *http.Client
-
-func init() {
+// newHTTPClient creates the default HTTP client for archival using the
+// specified transport. If transport is nil, http.DefaultTransport will be
+// used, so certificates are verified by default. Each archival uses its
+// own client, so cookies are never shared between archives.
+func newHTTPClient(transport http.RoundTripper) *http.Client {
 	jar, _ := cookiejar.New(nil)
-	httpClient = &http.Client{
-		Timeout: time.Minute,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
-		Jar: jar,
+	return &http.Client{
+		Timeout:   time.Minute,
+		Transport: transport,
+		Jar:       jar,
 	}
 }
@@ -0,0 +1,103 @@
+package archiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/go-shiori/warc/internal/processor"
+)
+
+// recomputeAllIntegrity recomputes the integrity attributes inside every
+// archived HTML. It's done once the whole archival is finished, since the
+// sub resources might be shared and still downloaded by another page.
+func (arc *Archiver) recomputeAllIntegrity() {
+	recomputed := make(map[string]struct{})
+	for _, report := range arc.Report().Resources {
+		if report.Processor != "html" || report.Err != nil {
+			continue
+		}
+
+		if _, exist := recomputed[report.Name]; exist {
+			continue
+		}
+		recomputed[report.Name] = struct{}{}
+
+		err := arc.recomputeIntegrity(report.Name)
+		if err != nil {
+			arc.logWarning("Failed to recompute integrity for %s: %v\n", report.URL, err)
+		}
+	}
+}
+
+// recomputeIntegrity updates the integrity attributes inside the saved
+// HTML resource, using the hash of the sub resources as they're archived.
+// It must be called after all sub resources of the HTML are saved.
+func (arc *Archiver) recomputeIntegrity(name string) error {
+	record, err := arc.Storage.GetResource(name)
+	if err != nil {
+		return err
+	}
+
+	content, err := arc.readContent(name)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Contains(content, []byte("integrity")) {
+		return nil
+	}
+
+	newContent, err := processor.RecomputeIntegrity(bytes.NewReader(content), func(subName string) []byte {
+		subContent, err := arc.readContent(subName)
+		if err != nil {
+			return nil
+		}
+		return subContent
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = arc.setContent(record, []byte(newContent))
+	if err != nil {
+		return err
+	}
+
+	err = arc.Storage.PutResource(name, record)
+	if err != nil {
+		return err
+	}
+
+	// Update statistic for manifest
+	arc.Lock()
+	arc.manifest.TotalSize += int64(len(newContent) - len(content))
+	arc.Unlock()
+
+	return nil
+}
+
+// readContent returns the decompressed content of the saved resource.
+func (arc *Archiver) readContent(name string) ([]byte, error) {
+	record, err := arc.Storage.GetResource(name)
+	if err != nil {
+		return nil, err
+	}
+
+	content := record["content"]
+	if ref := record["ref"]; content == nil && ref != nil && arc.BlobStore != nil {
+		content, err = arc.BlobStore.Get(string(ref))
+		if err != nil {
+			return nil, fmt.Errorf("blob store failed: %v", err)
+		}
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %v", name, err)
+	}
+
+	return ioutil.ReadAll(gzipReader)
+}
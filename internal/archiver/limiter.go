@@ -0,0 +1,194 @@
+package archiver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxConcurrency is the default number of
+	// downloads that can run at the same time.
+	DefaultMaxConcurrency = 5
+
+	// DefaultMaxHostConcurrency is the default number of downloads
+	// from the same host that can run at the same time.
+	DefaultMaxHostConcurrency = 2
+
+	// maxRetryAfter is the longest Retry-After that will be waited.
+	// If server asks for longer wait, the download is not retried.
+	maxRetryAfter = time.Minute
+)
+
+// limiter limits the downloads, both in total and for each host.
+type limiter struct {
+	sync.Mutex
+
+	workers      chan struct{}
+	hosts        map[string]*hostLimiter
+	hostCapacity int
+	hostDelay    time.Duration
+}
+
+// hostLimiter limits the downloads to a single host.
+type hostLimiter struct {
+	sync.Mutex
+
+	workers     chan struct{}
+	nextRequest time.Time
+}
+
+// newLimiter creates limiter that allows maxConcurrency downloads in total,
+// maxHostConcurrency downloads for each host, and waits at least hostDelay
+// between requests to the same host.
+func newLimiter(maxConcurrency, maxHostConcurrency int, hostDelay time.Duration) *limiter {
+	if maxConcurrency <= 0 {
+		maxConcurrency = DefaultMaxConcurrency
+	}
+
+	if maxHostConcurrency <= 0 {
+		maxHostConcurrency = DefaultMaxHostConcurrency
+	}
+
+	return &limiter{
+		workers:      make(chan struct{}, maxConcurrency),
+		hosts:        make(map[string]*hostLimiter),
+		hostCapacity: maxHostConcurrency,
+		hostDelay:    hostDelay,
+	}
+}
+
+// acquire waits until a download to the host is allowed. If it
+// succeed, release must be called once the download is finished.
+func (l *limiter) acquire(ctx context.Context, host string) (*hostLimiter, error) {
+	// Wait for the free worker in the host
+	h := l.host(host)
+	select {
+	case h.workers <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		// Wait until the delay for this host is passed. This is done
+		// without holding the global worker, so the other hosts are
+		// not blocked by the host that asks us to slow down.
+		h.Lock()
+		wait := time.Until(h.nextRequest)
+		h.Unlock()
+
+		if err := sleep(ctx, wait); err != nil {
+			<-h.workers
+			return nil, err
+		}
+
+		// Wait for the free worker in total
+		select {
+		case l.workers <- struct{}{}:
+		case <-ctx.Done():
+			<-h.workers
+			return nil, ctx.Err()
+		}
+
+		// The delay might be extended while waiting for the worker,
+		// e.g. by Retry-After, in which case wait again.
+		h.Lock()
+		if time.Now().Before(h.nextRequest) {
+			h.Unlock()
+			<-l.workers
+			continue
+		}
+
+		h.nextRequest = time.Now().Add(l.hostDelay)
+		h.Unlock()
+		return h, nil
+	}
+}
+
+// release marks the download to the host as finished.
+func (l *limiter) release(h *hostLimiter) {
+	<-l.workers
+	<-h.workers
+}
+
+// delay postpones the next request to the host, e.g. when the
+// server asks the client to slow down using Retry-After.
+func (l *limiter) delay(host string, d time.Duration) {
+	h := l.host(host)
+	h.Lock()
+	defer h.Unlock()
+
+	if next := time.Now().Add(d); next.After(h.nextRequest) {
+		h.nextRequest = next
+	}
+}
+
+// host returns the limiter for the specified host.
+func (l *limiter) host(host string) *hostLimiter {
+	l.Lock()
+	defer l.Unlock()
+
+	h, exist := l.hosts[host]
+	if !exist {
+		h = &hostLimiter{workers: make(chan struct{}, l.hostCapacity)}
+		l.hosts[host] = h
+	}
+
+	return h
+}
+
+// retryAfter returns how long the client should wait before repeating
+// the request, if the response is 429 or 503 with Retry-After header.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests &&
+		resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	// Retry-After is either delay in seconds or HTTP date
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	// The seconds is clamped, so the huge value doesn't overflow
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			seconds = 0
+		}
+		if maxSeconds := int64(maxRetryAfter/time.Second) + 1; seconds > maxSeconds {
+			seconds = maxSeconds
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// sleep pauses for the specified duration, unless the context is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,66 @@
+package archiver
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-shiori/warc/internal/storage"
+)
+
+// Version is the version of this library, which saved in archive manifest.
+const Version = "0.2.0"
+
+// FormatVersion is the version of archive's layout created by archiver.
+// It must be increased each time the layout is changed, e.g. the naming
+// or compression of the resources. Archives created before the format
+// version is introduced don't have manifest, and considered as version 0.
+//
+//   - Version 1 adds manifest, resource size and HTTP metadata.
+//   - Version 2 allows resource content to be referenced from blob store.
+const FormatVersion = 2
+
+// ManifestBucket is the name of reserved resource for archive's manifest.
+// Resource names are always prefixed by URL scheme (or "archive-root"
+// for the root), so it will never collide with any resource names.
+const ManifestBucket = "archive-manifest"
+
+// manifest is the metadata of the archived page.
+type manifest struct {
+	URL           string
+	FinalURL      string
+	Title         string
+	StartedAt     time.Time
+	ResourceCount int
+	TotalSize     int64
+	Failures      []Failure
+}
+
+// saveManifest saves the manifest as its reserved resource.
+func (arc *Archiver) saveManifest() error {
+	arc.RLock()
+	record := storage.Record{
+		"url":            []byte(arc.manifest.URL),
+		"final-url":      []byte(arc.manifest.FinalURL),
+		"title":          []byte(arc.manifest.Title),
+		"user-agent":     []byte(arc.UserAgent),
+		"version":        []byte(Version),
+		"format-version": []byte(strconv.Itoa(FormatVersion)),
+		"start-time":     []byte(arc.manifest.StartedAt.UTC().Format(time.RFC3339Nano)),
+		"end-time":       []byte(time.Now().UTC().Format(time.RFC3339Nano)),
+		"resource-count": []byte(strconv.Itoa(arc.manifest.ResourceCount)),
+		"total-size":     []byte(strconv.FormatInt(arc.manifest.TotalSize, 10)),
+	}
+
+	var err error
+	if len(arc.manifest.Failures) > 0 {
+		record["failures"], err = json.Marshal(arc.manifest.Failures)
+	}
+	arc.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return arc.Storage.PutResource(ManifestBucket, record)
+}
@@ -0,0 +1,69 @@
+package archiver
+
+import (
+	"time"
+)
+
+// Report is the result of the archival.
+type Report struct {
+	URL        string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Resources  []ResourceReport
+}
+
+// ResourceReport is the result of archiving a single resource.
+type ResourceReport struct {
+	URL        string
+	Name       string
+	Referrer   string
+	Processor  string
+	Size       int64
+	Duration   time.Duration
+	StatusCode int
+	Attempts   []Attempt
+	Err        error
+}
+
+// Succeeded returns the number of resources that successfully archived.
+func (r *Report) Succeeded() int {
+	count := 0
+	for _, res := range r.Resources {
+		if res.Err == nil {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Failed returns the resources that failed to be archived.
+func (r *Report) Failed() []ResourceReport {
+	failed := []ResourceReport{}
+	for _, res := range r.Resources {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+
+	return failed
+}
+
+// Report returns the report of the archival. It should
+// only be called after the archival is finished.
+func (arc *Archiver) Report() *Report {
+	arc.RLock()
+	defer arc.RUnlock()
+
+	report := arc.report
+	report.Resources = append([]ResourceReport(nil), arc.report.Resources...)
+	return &report
+}
+
+// addReport adds the result of archiving a resource into report.
+func (arc *Archiver) addReport(report ResourceReport) {
+	arc.Lock()
+	defer arc.Unlock()
+
+	arc.report.Resources = append(arc.report.Resources, report)
+}
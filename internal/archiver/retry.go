@@ -0,0 +1,110 @@
+package archiver
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy specifies how the failed downloads are retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	RetryStatus []int
+	RetryError  func(error) bool
+}
+
+// DefaultRetryPolicy returns the retry policy that used when
+// the MaxAttempts of the specified policy is zero.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+		RetryStatus: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryError: IsTemporaryError,
+	}
+}
+
+// Attempt is the result of a single attempt for downloading a resource.
+type Attempt struct {
+	StartedAt  time.Time     `json:"start"`
+	Duration   time.Duration `json:"duration"`
+	StatusCode int           `json:"status,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Failure is a resource that failed to be archived.
+type Failure struct {
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status,omitempty"`
+	Error      string    `json:"error"`
+	Attempts   []Attempt `json:"attempts,omitempty"`
+}
+
+// retryStatus checks if the download should be retried for the status code.
+func (p RetryPolicy) retryStatus(statusCode int) bool {
+	for _, code := range p.RetryStatus {
+		if code == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryError checks if the download should be retried for the error.
+func (p RetryPolicy) retryError(err error) bool {
+	if p.RetryError == nil {
+		return true
+	}
+
+	return p.RetryError(err)
+}
+
+// IsTemporaryError checks if the download error might go away when
+// retried, i.e. timeouts, temporary network errors and connections that
+// closed too early. The other errors like DNS and certificate errors
+// will only fail again.
+func IsTemporaryError(err error) bool {
+	if err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	if urlErr, ok := err.(*url.Error); ok && (urlErr.Err == io.EOF || urlErr.Err == io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	netErr, ok := err.(net.Error)
+	return ok && (netErr.Timeout() || netErr.Temporary())
+}
+
+// backoff returns the delay before the specified retry, starting from 1.
+// The delay is randomized between half and the full exponential backoff.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,143 @@
+package archiver
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	nurl "net/url"
+	"path"
+	"strings"
+)
+
+// extensionTypes is the content types for the common web extensions,
+// used when the system doesn't have them in its MIME database.
+var extensionTypes = map[string]string{
+	".css":   "text/css; charset=utf-8",
+	".js":    "text/javascript; charset=utf-8",
+	".mjs":   "text/javascript; charset=utf-8",
+	".json":  "application/json",
+	".html":  "text/html; charset=utf-8",
+	".htm":   "text/html; charset=utf-8",
+	".svg":   "image/svg+xml",
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".webp":  "image/webp",
+	".ico":   "image/x-icon",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".mp4":   "video/mp4",
+	".webm":  "video/webm",
+	".mp3":   "audio/mpeg",
+}
+
+// sniffContentType resolves the actual content type of the resource.
+// The type from the server is trusted unless it's missing or generic,
+// e.g. application/octet-stream. In that case the type is guessed, first
+// from the magic bytes of binary formats, then from the type declared by
+// the tag that refers the resource, then from the extension of the URL,
+// and finally from the content itself.
+func sniffContentType(headerType, declaredType, url string, content []byte) string {
+	detectedType := http.DetectContentType(content)
+	binaryDetected := isBinaryType(detectedType)
+
+	// Server might send wrong text type for binary file, e.g. image
+	// served as text/html, which can be spotted from its magic bytes.
+	if !isGenericType(headerType) {
+		if binaryDetected && isTextType(headerType) {
+			return detectedType
+		}
+		return headerType
+	}
+
+	if binaryDetected {
+		return detectedType
+	}
+
+	if isSVG(content) {
+		return "image/svg+xml"
+	}
+
+	if !isGenericType(declaredType) {
+		return declaredType
+	}
+
+	if extType := extensionType(url); extType != "" {
+		return extType
+	}
+
+	return detectedType
+}
+
+// isGenericType checks if the content type doesn't say anything
+// about the actual format of the content.
+func isGenericType(contentType string) bool {
+	baseType := mediaType(contentType)
+	switch baseType {
+	case "", "application/octet-stream", "binary/octet-stream",
+		"application/unknown", "application/x-download",
+		"text/plain", "*/*":
+		return true
+	default:
+		return false
+	}
+}
+
+// isTextType checks if the content type is for text document.
+func isTextType(contentType string) bool {
+	baseType := mediaType(contentType)
+	return strings.HasPrefix(baseType, "text/") ||
+		strings.HasSuffix(baseType, "/javascript") ||
+		strings.HasSuffix(baseType, "/json")
+}
+
+// isBinaryType checks if the content type detected from magic bytes
+// is for binary format. Text formats can't be detected reliably
+// by http.DetectContentType, so they are not trusted.
+func isBinaryType(contentType string) bool {
+	baseType := mediaType(contentType)
+	return baseType != "application/octet-stream" && !isTextType(baseType)
+}
+
+// isSVG checks if the content is SVG image, which is detected
+// as generic XML or text by http.DetectContentType.
+func isSVG(content []byte) bool {
+	if len(content) > 512 {
+		content = content[:512]
+	}
+
+	content = bytes.ToLower(bytes.TrimSpace(content))
+	if !bytes.HasPrefix(content, []byte("<")) {
+		return false
+	}
+
+	return bytes.Contains(content, []byte("<svg"))
+}
+
+// extensionType returns the content type from the extension of URL.
+func extensionType(url string) string {
+	parsedURL, err := nurl.Parse(url)
+	if err != nil {
+		return ""
+	}
+
+	ext := strings.ToLower(path.Ext(parsedURL.Path))
+	if ext == "" {
+		return ""
+	}
+
+	if contentType, exist := extensionTypes[ext]; exist {
+		return contentType
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+// mediaType returns the lowercased media type without its parameters.
+func mediaType(contentType string) string {
+	baseType := strings.SplitN(contentType, ";", 2)[0]
+	return strings.ToLower(strings.TrimSpace(baseType))
+}
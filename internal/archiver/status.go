@@ -0,0 +1,27 @@
+package archiver
+
+import (
+	"fmt"
+)
+
+// RootError is returned when the root page failed to be archived.
+type RootError struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+// Error returns the error message.
+func (e *RootError) Error() string {
+	return fmt.Sprintf("failed to archive root page %s: %v", e.URL, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RootError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultAcceptStatus accepts the successful responses, i.e. 2xx.
+func DefaultAcceptStatus(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
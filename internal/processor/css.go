@@ -38,27 +38,59 @@ func processCSS(input io.Reader, baseURL *nurl.URL) (string, []Resource) {
 	// Scan CSS file and process the resource's URL
 	lexer := css.NewLexer(input)
 	subResources := []Resource{}
+	inImport := false
 
 	for {
 		token, bt := lexer.Next()
+		importURL := false
 
 		// Check for error
 		if token == css.ErrorToken {
 			break
 		}
 
-		// If it's not an URL, just write it to buffer as it is
+		// The imported stylesheet might be written as plain string,
+		// e.g. `@import "theme.css";`, so it must be handled here.
+		// Since the stylesheet is processed as CSS file as well, its
+		// own imports will be archived recursively. The archiver only
+		// processes each URL once, so cyclic imports are stopped there.
+		if inImport {
+			switch token {
+			case css.WhitespaceToken, css.CommentToken:
+				buffer.Write(bt)
+				continue
+			case css.StringToken:
+				inImport = false
+				subResource, err := createResource(nil, sanitizeStyleURL(string(bt)), baseURL)
+				if err != nil {
+					buffer.Write(bt)
+					continue
+				}
+
+				subResource.DeclaredType = "text/css"
+				buffer.WriteString(`"` + subResource.Name + `"`)
+				subResources = append(subResources, subResource)
+				continue
+			default:
+				inImport = false
+				importURL = token == css.URLToken
+			}
+		}
+
+		if isImportRule(token, bt) {
+			inImport = true
+		}
+
+		// If it's not an URL, just write it to buffer as it is. This
+		// includes the format() hints in @font-face, since the font
+		// URLs inside src are always written using url().
 		if token != css.URLToken {
 			buffer.Write(bt)
 			continue
 		}
 
 		// Sanitize the URL by removing `url()`, quotation mark and trailing slash
-		cssURL := string(bt)
-		cssURL = rxStyleURL.ReplaceAllString(cssURL, "$1")
-		cssURL = strings.TrimSpace(cssURL)
-		cssURL = strings.Trim(cssURL, `'`)
-		cssURL = strings.Trim(cssURL, `"`)
+		cssURL := sanitizeStyleURL(string(bt))
 
 		// Create subresource from CSS URL
 		subResource, err := createResource(nil, cssURL, baseURL)
@@ -67,6 +99,11 @@ func processCSS(input io.Reader, baseURL *nurl.URL) (string, []Resource) {
 			continue
 		}
 
+		// The imported stylesheet is always CSS, whatever the server says
+		if importURL {
+			subResource.DeclaredType = "text/css"
+		}
+
 		// Write resource name instead of CSS URL
 		buffer.WriteString(`url("` + subResource.Name + `")`)
 
@@ -77,3 +114,17 @@ func processCSS(input io.Reader, baseURL *nurl.URL) (string, []Resource) {
 	// Return the new rule after all URL has been processed
 	return buffer.String(), subResources
 }
+
+// isImportRule checks if the token is the keyword of @import rule.
+func isImportRule(token css.TokenType, bt []byte) bool {
+	return token == css.AtKeywordToken && strings.EqualFold(string(bt), "@import")
+}
+
+// sanitizeStyleURL removes `url()` and quotation mark from CSS URL token.
+func sanitizeStyleURL(cssURL string) string {
+	cssURL = rxStyleURL.ReplaceAllString(cssURL, "$1")
+	cssURL = strings.TrimSpace(cssURL)
+	cssURL = strings.Trim(cssURL, `'`)
+	cssURL = strings.Trim(cssURL, `"`)
+	return cssURL
+}
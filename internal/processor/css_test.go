@@ -0,0 +1,40 @@
+package processor
+
+import (
+	nurl "net/url"
+	"strings"
+	"testing"
+)
+
+func TestProcessCSSDeclaredType(t *testing.T) {
+	baseURL, _ := nurl.Parse("http://example.com/")
+	rules := `@import "a.css";` +
+		`@import url("/css?family=b");` +
+		`@import url(c.css) screen;` +
+		`body { background: url("bg.png"); }`
+
+	_, subResources := processCSS(strings.NewReader(rules), baseURL)
+
+	want := map[string]string{
+		"http://example.com/a.css":        "text/css",
+		"http://example.com/css?family=b": "text/css",
+		"http://example.com/c.css":        "text/css",
+		"http://example.com/bg.png":       "",
+	}
+
+	if len(subResources) != len(want) {
+		t.Fatalf("got %d sub resources, want %d", len(subResources), len(want))
+	}
+
+	for _, res := range subResources {
+		wantType, exist := want[res.URL]
+		if !exist {
+			t.Errorf("unexpected sub resource %s", res.URL)
+			continue
+		}
+
+		if res.DeclaredType != wantType {
+			t.Errorf("declared type of %s = %q, want %q", res.URL, res.DeclaredType, wantType)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package processor
 
 import (
 	"fmt"
+	"io"
 	nurl "net/url"
 	"regexp"
 	"strings"
@@ -30,41 +31,52 @@ func ProcessHTMLFile(req Request) (Resource, []Resource, error) {
 		return Resource{}, nil, fmt.Errorf("failed to parse HTML for %s: %v", req.URL, err)
 	}
 
-	// TODO: I'm still not really sure, but IMHO it's safer to
-	// disable Javascript. Ideally, we only want to remove XHR request
-	// using disableXHR(). Unfortunately, the result is not that good for now.
-	dom.RemoveNodes(dom.GetElementsByTagName(doc, "script"), nil)
+	// By default it's safer to disable Javascript. If the scripts are
+	// kept, the URLs inside them will be processed later along with the
+	// other tags, and for sandbox the network APIs are disabled first.
+	switch req.ScriptPolicy {
+	case ScriptKeep:
+	case ScriptSandbox:
+		disableXHR(doc)
+	default:
+		dom.RemoveNodes(dom.GetElementsByTagName(doc, "script"), nil)
+	}
+
+	// Relative URLs are resolved against <base href> if it exists
+	baseURL := resolveBaseURL(doc, pageURL)
 
 	// Convert lazy loaded image to normal
 	fixLazyImages(doc)
 
 	// Convert hyperlinks with relative URL
-	fixRelativeURIs(doc, pageURL)
+	fixRelativeURIs(doc, baseURL, req.ScriptPolicy == ScriptRemove)
 
 	// Extract subresources from each nodes
 	subResources := []Resource{}
 	for _, node := range dom.GetElementsByTagName(doc, "*") {
 		// First extract resources from inline style
-		cssResources := processInlineCSS(node, pageURL)
+		cssResources := processInlineCSS(node, baseURL)
 		subResources = append(subResources, cssResources...)
 
 		// Next extract resources from tag's specific attribute
 		nodeResources := []Resource{}
 		switch dom.TagName(node) {
 		case "style":
-			nodeResources = processStyleTag(node, pageURL)
+			nodeResources = processStyleTag(node, baseURL)
 		case "script":
-			nodeResources = processScriptTag(node, pageURL)
+			nodeResources = processScriptTag(node, baseURL)
+			processIntegrity(node, req.IntegrityPolicy)
 		case "meta":
-			nodeResources = processMetaTag(node, pageURL)
+			nodeResources = processMetaTag(node, baseURL)
 		case "img", "picture", "figure", "video", "audio", "source":
-			nodeResources = processMediaTag(node, pageURL)
+			nodeResources = processMediaTag(node, baseURL)
 		case "link":
-			nodeResources = processGenericTag(node, "href", pageURL)
+			nodeResources = processGenericTag(node, "href", baseURL)
+			processIntegrity(node, req.IntegrityPolicy)
 		case "iframe":
-			nodeResources = processGenericTag(node, "src", pageURL)
+			nodeResources = processGenericTag(node, "src", baseURL)
 		case "object":
-			nodeResources = processGenericTag(node, "data", pageURL)
+			nodeResources = processGenericTag(node, "data", baseURL)
 		default:
 			continue
 		}
@@ -74,10 +86,38 @@ func ProcessHTMLFile(req Request) (Resource, []Resource, error) {
 	// Return outer HTML of the doc
 	outerHTML := dom.OuterHTML(doc)
 	resource, err := createResource([]byte(outerHTML), req.URL, nil)
+	if err != nil {
+		return Resource{}, nil, err
+	}
+
+	resource.Title = documentTitle(doc)
+	return resource, subResources, nil
+}
+
+// ExtractTitle returns the title of the HTML document.
+func ExtractTitle(input io.Reader) (string, error) {
+	doc, err := html.Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %v", err)
+	}
+
+	return documentTitle(doc), nil
+}
+
+// documentTitle returns text content of the first <title> in document.
+func documentTitle(doc *html.Node) string {
+	titles := dom.GetElementsByTagName(doc, "title")
+	if len(titles) == 0 {
+		return ""
+	}
 
-	return resource, subResources, err
+	return strings.TrimSpace(dom.TextContent(titles[0]))
 }
 
+// disableXHR disables the network APIs used by scripts, e.g. fetch,
+// XMLHttpRequest and WebSocket, so the archived page can't load any data
+// from internet. Since the shim might be bypassed, the connections are
+// also blocked using Content Security Policy.
 func disableXHR(doc *html.Node) {
 	var head *html.Node
 	heads := dom.GetElementsByTagName(doc, "head")
@@ -89,28 +129,85 @@ func disableXHR(doc *html.Node) {
 	}
 
 	xhrDisabler := `
-	fetch = new Promise();
-
-	XMLHttpRequest = function() {};
-	XMLHttpRequest.prototype = {
-		open: function(){},
-		send: function(){},
-		abort: function(){},
-		setRequestHeader: function(){},
-		overrideMimeType: function(){},
-		getResponseHeaders(): function(){},
-		getAllResponseHeaders(): function(){},
-	};`
+	(function() {
+		var noop = function() {};
+		var blocked = function() {
+			throw new Error("network is disabled in archived page");
+		};
+
+		window.fetch = function() {
+			return Promise.reject(new TypeError("network is disabled in archived page"));
+		};
+
+		window.XMLHttpRequest = function() {};
+		window.XMLHttpRequest.prototype = {
+			open: noop,
+			send: noop,
+			abort: noop,
+			setRequestHeader: noop,
+			overrideMimeType: noop,
+			getResponseHeader: function() { return null; },
+			getAllResponseHeaders: function() { return ""; },
+			addEventListener: noop,
+			removeEventListener: noop
+		};
+
+		window.WebSocket = blocked;
+		window.EventSource = blocked;
+		if (window.navigator && window.navigator.sendBeacon) {
+			window.navigator.sendBeacon = function() { return false; };
+		}
+	})();`
 
 	script := dom.CreateElement("script")
 	scriptContent := dom.CreateTextNode(xhrDisabler)
-	dom.PrependChild(script, scriptContent)
+	dom.AppendChild(script, scriptContent)
+
+	csp := dom.CreateElement("meta")
+	dom.SetAttribute(csp, "http-equiv", "Content-Security-Policy")
+	dom.SetAttribute(csp, "content", "connect-src 'none'")
+
 	dom.PrependChild(head, script)
+	dom.PrependChild(head, csp)
+}
+
+// resolveBaseURL returns the URL for resolving relative URLs inside the
+// document, i.e. the first <base href> resolved against the page URL. Since
+// the archived resources are referenced by their name, which is relative to
+// the archive, the href of <base> is removed afterward. If the <base> has
+// no other attributes (e.g. target), it will be removed as well.
+func resolveBaseURL(doc *html.Node, pageURL *nurl.URL) *nurl.URL {
+	baseURL := pageURL
+	found := false
+
+	for _, base := range dom.GetElementsByTagName(doc, "base") {
+		if !dom.HasAttribute(base, "href") {
+			continue
+		}
+
+		// Only the first <base> with href is used by browser
+		href := strings.TrimSpace(dom.GetAttribute(base, "href"))
+		if !found && href != "" {
+			tmp, err := pageURL.Parse(href)
+			if err == nil && rxHTTPScheme.MatchString(tmp.String()) {
+				baseURL = tmp
+			}
+		}
+
+		found = true
+		dom.RemoveAttribute(base, "href")
+		if len(base.Attr) == 0 && base.Parent != nil {
+			base.Parent.RemoveChild(base)
+		}
+	}
+
+	return baseURL
 }
 
 // fixRelativeURIs converts each <a> in the given element
-// to an absolute URI, ignoring #ref URIs.
-func fixRelativeURIs(doc *html.Node, pageURL *nurl.URL) {
+// to an absolute URI, ignoring #ref URIs. If scripts are
+// removed, the javascript: links will be removed as well.
+func fixRelativeURIs(doc *html.Node, pageURL *nurl.URL, scriptRemoved bool) {
 	links := dom.GetAllNodesWithTag(doc, "a")
 	dom.ForEachNode(links, func(link *html.Node, _ int) {
 		href := dom.GetAttribute(link, "href")
@@ -120,10 +217,12 @@ func fixRelativeURIs(doc *html.Node, pageURL *nurl.URL) {
 
 		// Replace links with javascript: URIs with text content,
 		// since they won't work after scripts have been removed
-		// from the page.
+		// from the page. If scripts are kept, leave them as it is.
 		if strings.HasPrefix(href, "javascript:") {
-			text := dom.CreateTextNode(dom.TextContent(link))
-			dom.ReplaceChild(link.Parent, text, link)
+			if scriptRemoved {
+				text := dom.CreateTextNode(dom.TextContent(link))
+				dom.ReplaceChild(link.Parent, text, link)
+			}
 		} else {
 			newHref := createAbsoluteURL(href, pageURL)
 			if newHref == "" {
@@ -223,6 +322,12 @@ func processScriptTag(node *html.Node, pageURL *nurl.URL) []Resource {
 	// Also get the URL from `src` attribute
 	subResources := processGenericTag(node, "src", pageURL)
 
+	// Only process the actual JS code, since <script> is
+	// also used for data and template, e.g. JSON-LD.
+	if !isJSScript(node) {
+		return subResources
+	}
+
 	// Extract JS code from the <script> itself
 	script := dom.TextContent(node)
 	script = strings.TrimSpace(script)
@@ -239,6 +344,12 @@ func processScriptTag(node *html.Node, pageURL *nurl.URL) []Resource {
 	return subResources
 }
 
+// isJSScript checks if the <script> contains JS code.
+func isJSScript(node *html.Node) bool {
+	scriptType := strings.ToLower(strings.TrimSpace(dom.GetAttribute(node, "type")))
+	return scriptType == "" || scriptType == "module" || rxJSContentType.MatchString(scriptType)
+}
+
 // extractMetaTag extract archive's resource from inside a <meta>.
 // Normally, <meta> doesn't have any resource URLs. However, as
 // social media come and grow, a new metadata is added to contain
@@ -291,6 +402,10 @@ func processMediaTag(node *html.Node, pageURL *nurl.URL) []Resource {
 			continue
 		}
 
+		if attrName == "src" {
+			subResource.DeclaredType = declaredType(node)
+		}
+
 		dom.SetAttribute(node, attrName, subResource.Name)
 		subResources = append(subResources, subResource)
 	}
@@ -343,6 +458,41 @@ func processGenericTag(node *html.Node, attrName string, pageURL *nurl.URL) []Re
 		subResource.IsEmbed = true
 	}
 
+	subResource.DeclaredType = declaredType(node)
 	dom.SetAttribute(node, attrName, subResource.Name)
 	return []Resource{subResource}
 }
+
+// declaredType returns the content type that expected by the tag for
+// its resource, either from its type attribute or from its purpose.
+// Returns empty string if the tag doesn't specify any.
+func declaredType(node *html.Node) string {
+	attrType := strings.ToLower(strings.TrimSpace(dom.GetAttribute(node, "type")))
+
+	switch dom.TagName(node) {
+	case "script":
+		if isJSScript(node) {
+			return "text/javascript"
+		}
+	case "link":
+		if attrType != "" {
+			break
+		}
+
+		rels := strings.Fields(strings.ToLower(dom.GetAttribute(node, "rel")))
+		for _, rel := range rels {
+			if rel == "stylesheet" {
+				return "text/css"
+			}
+		}
+
+		switch strings.ToLower(dom.GetAttribute(node, "as")) {
+		case "style":
+			return "text/css"
+		case "script":
+			return "text/javascript"
+		}
+	}
+
+	return attrType
+}
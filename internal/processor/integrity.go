@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// ContentFunc returns the archived content of the specified resource name.
+// If it returns nil, the resource is considered as not archived.
+type ContentFunc func(name string) []byte
+
+// RecomputeIntegrity updates the integrity attributes inside an archived
+// HTML file using the hash of the archived resources returned by fn. The
+// hash algorithm follows the strongest one in the original attribute. If
+// the resource is not archived, the integrity attribute is removed.
+func RecomputeIntegrity(input io.Reader, fn ContentFunc) (string, error) {
+	doc, err := html.Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %v", err)
+	}
+
+	nodes := dom.GetAllNodesWithTag(doc, "link", "script")
+	dom.ForEachNode(nodes, func(node *html.Node, _ int) {
+		integrity := dom.GetAttribute(node, "integrity")
+		if integrity == "" {
+			return
+		}
+
+		attrName := "src"
+		if dom.TagName(node) == "link" {
+			attrName = "href"
+		}
+
+		content := fn(strings.TrimSpace(dom.GetAttribute(node, attrName)))
+		if content == nil {
+			stripIntegrity(node)
+			return
+		}
+
+		dom.SetAttribute(node, "integrity", computeIntegrity(integrity, content))
+	})
+
+	return dom.OuterHTML(doc), nil
+}
+
+// processIntegrity handles the integrity attribute of
+// the node following the specified policy.
+func processIntegrity(node *html.Node, policy IntegrityPolicy) {
+	if policy == IntegrityStrip {
+		stripIntegrity(node)
+	}
+}
+
+// stripIntegrity removes the integrity attribute, along
+// with crossorigin which is only needed for checking it.
+func stripIntegrity(node *html.Node) {
+	if !dom.HasAttribute(node, "integrity") {
+		return
+	}
+
+	dom.RemoveAttribute(node, "integrity")
+	dom.RemoveAttribute(node, "crossorigin")
+}
+
+// computeIntegrity returns the integrity metadata for the content, using
+// the strongest hash algorithm in the original metadata. If there are no
+// known algorithms, SHA-384 is used as recommended by the specification.
+func computeIntegrity(original string, content []byte) string {
+	algorithm := "sha384"
+	strength := 0
+	for _, metadata := range strings.Fields(original) {
+		prefix := strings.ToLower(strings.SplitN(metadata, "-", 2)[0])
+		switch {
+		case prefix == "sha512" && strength < 3:
+			algorithm, strength = prefix, 3
+		case prefix == "sha384" && strength < 2:
+			algorithm, strength = prefix, 2
+		case prefix == "sha256" && strength < 1:
+			algorithm, strength = prefix, 1
+		}
+	}
+
+	var hasher hash.Hash
+	switch algorithm {
+	case "sha512":
+		hasher = sha512.New()
+	case "sha256":
+		hasher = sha256.New()
+	default:
+		hasher = sha512.New384()
+	}
+
+	hasher.Write(content)
+	return algorithm + "-" + base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+}
@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+func parseNode(t *testing.T, src, tagName string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse HTML: %v", err)
+	}
+
+	nodes := dom.GetElementsByTagName(doc, tagName)
+	if len(nodes) == 0 {
+		t.Fatalf("no <%s> in %q", tagName, src)
+	}
+
+	return nodes[0]
+}
+
+func TestProcessIntegrity(t *testing.T) {
+	src := `<link rel="stylesheet" href="a.css" integrity="sha384-AAAA" crossorigin="anonymous">`
+
+	tests := []struct {
+		name          string
+		policy        IntegrityPolicy
+		wantIntegrity bool
+	}{
+		{"strip", IntegrityStrip, false},
+		{"recompute", IntegrityRecompute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := parseNode(t, src, "link")
+			processIntegrity(node, tt.policy)
+
+			if got := dom.HasAttribute(node, "integrity"); got != tt.wantIntegrity {
+				t.Errorf("integrity exists = %v, want %v", got, tt.wantIntegrity)
+			}
+			if got := dom.HasAttribute(node, "crossorigin"); got != tt.wantIntegrity {
+				t.Errorf("crossorigin exists = %v, want %v", got, tt.wantIntegrity)
+			}
+		})
+	}
+}
+
+func TestProcessIntegrityWithoutAttribute(t *testing.T) {
+	// The crossorigin is only removed along with integrity, since
+	// it's also used for things like CORS-enabled images.
+	node := parseNode(t, `<script src="a.js" crossorigin="anonymous"></script>`, "script")
+	processIntegrity(node, IntegrityStrip)
+
+	if !dom.HasAttribute(node, "crossorigin") {
+		t.Errorf("crossorigin is removed without integrity")
+	}
+}
+
+func TestProcessHTMLFileIntegrity(t *testing.T) {
+	src := `<html><head>` +
+		`<link rel="stylesheet" href="/a.css" integrity="sha384-AAAA" crossorigin="anonymous">` +
+		`<script src="/b.js" integrity="sha256-BBBB" crossorigin></script>` +
+		`</head><body></body></html>`
+
+	tests := []struct {
+		name          string
+		policy        IntegrityPolicy
+		wantIntegrity int
+	}{
+		{"strip", IntegrityStrip, 0},
+		{"recompute", IntegrityRecompute, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, _, err := ProcessHTMLFile(Request{
+				Reader:          strings.NewReader(src),
+				URL:             "http://example.com/",
+				ScriptPolicy:    ScriptKeep,
+				IntegrityPolicy: tt.policy,
+			})
+			if err != nil {
+				t.Fatalf("failed to process HTML: %v", err)
+			}
+
+			if got := strings.Count(string(resource.Content), "integrity="); got != tt.wantIntegrity {
+				t.Errorf("got %d integrity attributes, want %d", got, tt.wantIntegrity)
+			}
+		})
+	}
+}
+
+func TestRecomputeIntegrity(t *testing.T) {
+	src := `<html><head>` +
+		`<link rel="stylesheet" href="a.css" integrity="sha384-AAAA" crossorigin="anonymous">` +
+		`<script src="missing.js" integrity="sha384-BBBB" crossorigin="anonymous"></script>` +
+		`</head><body></body></html>`
+
+	content := []byte("body{color:red}")
+	result, err := RecomputeIntegrity(strings.NewReader(src), func(name string) []byte {
+		if name == "a.css" {
+			return content
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to recompute integrity: %v", err)
+	}
+
+	doc, err := html.Parse(strings.NewReader(result))
+	if err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+
+	sum := sha512.Sum384(content)
+	want := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	link := dom.GetElementsByTagName(doc, "link")[0]
+	if got := dom.GetAttribute(link, "integrity"); got != want {
+		t.Errorf("link integrity = %q, want %q", got, want)
+	}
+
+	script := dom.GetElementsByTagName(doc, "script")[0]
+	if dom.HasAttribute(script, "integrity") || dom.HasAttribute(script, "crossorigin") {
+		t.Errorf("integrity of missing resource is not stripped: %s", dom.OuterHTML(script))
+	}
+}
+
+func TestComputeIntegrity(t *testing.T) {
+	content := []byte("var x = 1;")
+	sum256 := sha256.Sum256(content)
+	sum384 := sha512.Sum384(content)
+	sum512 := sha512.Sum512(content)
+
+	tests := []struct {
+		name     string
+		original string
+		want     string
+	}{
+		{"sha256", "sha256-AAAA", "sha256-" + base64.StdEncoding.EncodeToString(sum256[:])},
+		{"sha384 over sha256", "sha256-AAAA sha384-BBBB", "sha384-" + base64.StdEncoding.EncodeToString(sum384[:])},
+		{"sha512 over the others", "sha384-BBBB sha512-CCCC sha256-AAAA", "sha512-" + base64.StdEncoding.EncodeToString(sum512[:])},
+		{"case insensitive", "SHA512-CCCC", "sha512-" + base64.StdEncoding.EncodeToString(sum512[:])},
+		{"unknown algorithm", "md5-DDDD", "sha384-" + base64.StdEncoding.EncodeToString(sum384[:])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeIntegrity(tt.original, content); got != tt.want {
+				t.Errorf("computeIntegrity(%q) = %q, want %q", tt.original, got, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,25 @@ var (
 	rxJSContentType = regexp.MustCompile(`(?i)(text|application)/(java|ecma)script`)
 )
 
+// ProcessJSFile process JS file.
+func ProcessJSFile(req Request) (Resource, []Resource, error) {
+	// Parse URL, then use it to extract resource URLs
+	parsedURL, err := nurl.ParseRequestURI(req.URL)
+	if err != nil || parsedURL.Scheme == "" || parsedURL.Hostname() == "" {
+		return Resource{}, nil, fmt.Errorf("url %s is not valid", req.URL)
+	}
+
+	script, subResources := processJS(req.Reader, parsedURL)
+	resource, err := createResource([]byte(script), req.URL, nil)
+
+	return resource, subResources, err
+}
+
+// IsJSContentType checks if the content type is for JS file.
+func IsJSContentType(contentType string) bool {
+	return rxJSContentType.MatchString(contentType)
+}
+
 // processJavascript extract resource URLs from the specified JS input.
 // Returns the new rules with all URLs updated to the archival link.
 func processJS(input io.Reader, baseURL *nurl.URL) (string, []Resource) {
@@ -14,19 +14,53 @@ var (
 	rxTrailingSlash = regexp.MustCompile(`(?i)/+$`)
 )
 
+// ScriptPolicy decides how the scripts inside HTML are archived.
+type ScriptPolicy int
+
+const (
+	// ScriptRemove removes all scripts from the HTML.
+	ScriptRemove ScriptPolicy = iota
+
+	// ScriptKeep keeps the scripts, with the resource URLs
+	// inside them rewritten to the archived resources.
+	ScriptKeep
+
+	// ScriptSandbox is like ScriptKeep, but the network APIs
+	// are disabled so the scripts can't fetch any data.
+	ScriptSandbox
+)
+
+// IntegrityPolicy decides how the integrity attributes inside HTML are handled.
+type IntegrityPolicy int
+
+const (
+	// IntegrityStrip removes the integrity and crossorigin attributes.
+	IntegrityStrip IntegrityPolicy = iota
+
+	// IntegrityRecompute keeps the attributes, which then must be
+	// recomputed using RecomputeIntegrity once the resources are saved.
+	IntegrityRecompute
+)
+
 // Request is struct that contains data that want to be processed.
 type Request struct {
-	Reader io.Reader
-	URL    string
+	Reader          io.Reader
+	URL             string
+	ScriptPolicy    ScriptPolicy
+	IntegrityPolicy IntegrityPolicy
 }
 
 // Resource is struct that contains URL for downloading
-// and archiving a resource.
+// and archiving a resource. Title is only available for HTML.
+// DeclaredType is the content type that expected by the tag or
+// rule that refers the resource, e.g. text/css for stylesheet.
 type Resource struct {
-	Name    string
-	URL     string
-	Content []byte
-	IsEmbed bool
+	Name         string
+	URL          string
+	Title        string
+	Content      []byte
+	IsEmbed      bool
+	DeclaredType string
 }
 
 func createResource(content []byte, url string, baseURL *nurl.URL) (Resource, error) {
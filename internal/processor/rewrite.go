@@ -0,0 +1,226 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-shiori/dom"
+	"github.com/tdewolff/parse/css"
+	"github.com/tdewolff/parse/js"
+	"golang.org/x/net/html"
+)
+
+// RewriteFunc returns the replacement for the specified resource name.
+// If it returns empty string, the resource name will be left as it is.
+type RewriteFunc func(name string) string
+
+// RewriteHTMLFile replaces resource names inside an archived HTML file
+// using the specified function. It's the counterpart of ProcessHTMLFile,
+// so it only visits the attributes that modified by ProcessHTMLFile.
+// Since the rewritten resources no longer match the archived hashes,
+// the integrity attributes are removed.
+func RewriteHTMLFile(input io.Reader, fn RewriteFunc) (string, error) {
+	doc, err := html.Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %v", err)
+	}
+
+	for _, node := range dom.GetElementsByTagName(doc, "*") {
+		// Rewrite inline style
+		if style := dom.GetAttribute(node, "style"); strings.TrimSpace(style) != "" {
+			newStyle := RewriteCSSFile(strings.NewReader(style), fn)
+			dom.SetAttribute(node, "style", newStyle)
+		}
+
+		// Rewrite tag's specific attribute
+		switch dom.TagName(node) {
+		case "style":
+			rules := dom.TextContent(node)
+			dom.SetTextContent(node, RewriteCSSFile(strings.NewReader(rules), fn))
+		case "script":
+			rewriteAttribute(node, "src", fn)
+			stripIntegrity(node)
+			if script := dom.TextContent(node); isJSScript(node) && strings.TrimSpace(script) != "" {
+				dom.SetTextContent(node, RewriteJSFile(strings.NewReader(script), fn))
+			}
+		case "meta":
+			rewriteAttribute(node, "content", fn)
+		case "img", "picture", "figure", "video", "audio", "source":
+			rewriteAttribute(node, "src", fn)
+			rewriteAttribute(node, "poster", fn)
+			rewriteSrcset(node, fn)
+		case "link":
+			rewriteAttribute(node, "href", fn)
+			stripIntegrity(node)
+		case "iframe":
+			rewriteAttribute(node, "src", fn)
+		case "object":
+			rewriteAttribute(node, "data", fn)
+		}
+	}
+
+	return dom.OuterHTML(doc), nil
+}
+
+// InlineStylesheets replaces each <link rel="stylesheet"> with <style>
+// that contains the CSS rules returned by fn for the link's href. If fn
+// returns empty string, the <link> will be left as it is.
+func InlineStylesheets(input io.Reader, fn RewriteFunc) (string, error) {
+	doc, err := html.Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %v", err)
+	}
+
+	links := dom.GetElementsByTagName(doc, "link")
+	dom.ForEachNode(links, func(link *html.Node, _ int) {
+		rel := strings.ToLower(dom.GetAttribute(link, "rel"))
+		if !strings.Contains(rel, "stylesheet") || link.Parent == nil {
+			return
+		}
+
+		href := strings.TrimSpace(dom.GetAttribute(link, "href"))
+		if href == "" {
+			return
+		}
+
+		rules := fn(href)
+		if rules == "" {
+			return
+		}
+
+		style := dom.CreateElement("style")
+		if media := dom.GetAttribute(link, "media"); media != "" {
+			dom.SetAttribute(style, "media", media)
+		}
+
+		dom.SetTextContent(style, rules)
+		dom.ReplaceChild(link.Parent, style, link)
+	})
+
+	return dom.OuterHTML(doc), nil
+}
+
+// RewriteCSSFile replaces resource names inside an archived CSS
+// using the specified function.
+func RewriteCSSFile(input io.Reader, fn RewriteFunc) string {
+	buffer := bytes.NewBuffer(nil)
+	lexer := css.NewLexer(input)
+	inImport := false
+
+	for {
+		token, bt := lexer.Next()
+		if token == css.ErrorToken {
+			break
+		}
+
+		// Rewrite the stylesheet that imported as plain string
+		if inImport {
+			switch token {
+			case css.WhitespaceToken, css.CommentToken:
+				buffer.Write(bt)
+				continue
+			case css.StringToken:
+				inImport = false
+				if newURL := fn(sanitizeStyleURL(string(bt))); newURL != "" {
+					buffer.WriteString(`"` + newURL + `"`)
+				} else {
+					buffer.Write(bt)
+				}
+				continue
+			default:
+				inImport = false
+			}
+		}
+
+		if isImportRule(token, bt) {
+			inImport = true
+		}
+
+		if token != css.URLToken {
+			buffer.Write(bt)
+			continue
+		}
+
+		newURL := fn(sanitizeStyleURL(string(bt)))
+		if newURL == "" {
+			buffer.Write(bt)
+			continue
+		}
+
+		buffer.WriteString(`url("` + newURL + `")`)
+	}
+
+	return buffer.String()
+}
+
+// RewriteJSFile replaces resource names inside an archived JS using the
+// specified function. Only the strings that contain exactly a resource
+// name, either as it is or inside `url()`, are replaced.
+func RewriteJSFile(input io.Reader, fn RewriteFunc) string {
+	buffer := bytes.NewBuffer(nil)
+	lexer := js.NewLexer(input)
+
+	for {
+		token, bt := lexer.Next()
+		if token == js.ErrorToken {
+			break
+		}
+
+		if token != js.StringToken {
+			buffer.Write(bt)
+			continue
+		}
+
+		text := strings.Trim(strings.TrimSpace(string(bt)), `'"`)
+		if strings.HasPrefix(text, "url(") {
+			if newURL := fn(sanitizeStyleURL(text)); newURL != "" {
+				buffer.WriteString(fmt.Sprintf("\"url('%s')\"", newURL))
+				continue
+			}
+		} else if newURL := fn(text); newURL != "" {
+			buffer.WriteString(fmt.Sprintf("%q", newURL))
+			continue
+		}
+
+		buffer.Write(bt)
+	}
+
+	return buffer.String()
+}
+
+// rewriteAttribute replaces the value of specified attribute
+// if it's a resource name that recognized by fn.
+func rewriteAttribute(node *html.Node, attrName string, fn RewriteFunc) {
+	attrValue := strings.TrimSpace(dom.GetAttribute(node, attrName))
+	if attrValue == "" {
+		return
+	}
+
+	if newValue := fn(attrValue); newValue != "" {
+		dom.SetAttribute(node, attrName, newValue)
+	}
+}
+
+// rewriteSrcset replaces each URL inside srcset attribute.
+func rewriteSrcset(node *html.Node, fn RewriteFunc) {
+	strSrcSets := dom.GetAttribute(node, "srcset")
+	if strSrcSets == "" {
+		return
+	}
+
+	srcSets := strings.Split(strSrcSets, ",")
+	for i, srcSet := range srcSets {
+		parts := strings.SplitN(strings.TrimSpace(srcSet), " ", 2)
+		if parts[0] == "" {
+			continue
+		}
+
+		if newURL := fn(parts[0]); newURL != "" {
+			srcSets[i] = strings.Replace(srcSets[i], parts[0], newURL, 1)
+		}
+	}
+
+	dom.SetAttribute(node, "srcset", strings.Join(srcSets, ","))
+}
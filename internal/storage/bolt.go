@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"os"
+	fp "path/filepath"
+
+	"go.etcd.io/bbolt"
+)
+
+// Bolt is storage that saves the resources inside a bolt database,
+// where each resource is saved in its own bucket.
+type Bolt struct {
+	db *bbolt.DB
+}
+
+// OpenBolt opens bolt database in specified path as storage.
+// If it's not read only, the database will be created if needed.
+func OpenBolt(path string, readOnly bool) (*Bolt, error) {
+	if !readOnly {
+		os.MkdirAll(fp.Dir(path), os.ModePerm)
+	}
+
+	options := &bbolt.Options{ReadOnly: readOnly}
+	db, err := bbolt.Open(path, os.ModePerm, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// PutResource saves the record for resource with specified name.
+func (b *Bolt) PutResource(name string, record Record) error {
+	return b.db.Batch(func(tx *bbolt.Tx) error {
+		if tx.Bucket([]byte(name)) != nil {
+			err := tx.DeleteBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+
+		bucket, err := tx.CreateBucket([]byte(name))
+		if err != nil {
+			return err
+		}
+
+		for key, value := range record {
+			err = bucket.Put([]byte(key), value)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetResource returns the complete record of the resource.
+func (b *Bolt) GetResource(name string) (Record, error) {
+	return b.getRecord(name, true)
+}
+
+// StatResource returns the record of the resource without its content.
+func (b *Bolt) StatResource(name string) (Record, error) {
+	return b.getRecord(name, false)
+}
+
+// ListResources returns names of all resources, sorted by their name.
+func (b *Bolt) ListResources() ([]string, error) {
+	names := []string{}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			names = append(names, string(name))
+			return nil
+		})
+	})
+
+	return names, err
+}
+
+// Close closes the database.
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+// getRecord reads the record from bucket. The values must be
+// copied since they are only valid inside the transaction.
+func (b *Bolt) getRecord(name string, withContent bool) (Record, error) {
+	var record Record
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(name))
+		if bucket == nil {
+			return ErrNotExist
+		}
+
+		record = Record{}
+		return bucket.ForEach(func(key, value []byte) error {
+			if string(key) == "content" && !withContent {
+				return nil
+			}
+
+			record[string(key)] = append([]byte(nil), value...)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
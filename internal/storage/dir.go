@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	fp "path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Dir is storage that saves the resources as plain directory tree. Each
+// resource is saved in its own directory, which contains a file for each
+// of its fields and `.name` file that contains the resource name.
+type Dir struct {
+	sync.RWMutex
+	dir string
+}
+
+// OpenDir opens directory in specified path as storage.
+// The directory will be created if needed.
+func OpenDir(dir string) (*Dir, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dir{dir: dir}, nil
+}
+
+// PutResource saves the record for resource with specified name. The record
+// is written into temporary directory first, then moved to its place while
+// the readers are locked out, so they never see a partial or missing one.
+func (d *Dir) PutResource(name string, record Record) error {
+	tmpDir, err := ioutil.TempDir(d.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = ioutil.WriteFile(fp.Join(tmpDir, ".name"), []byte(name), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range record {
+		err = ioutil.WriteFile(fp.Join(tmpDir, key), value, os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	entryPath := fp.Join(d.dir, entryName(name))
+	err = os.RemoveAll(entryPath)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpDir, entryPath)
+}
+
+// GetResource returns the complete record of the resource.
+func (d *Dir) GetResource(name string) (Record, error) {
+	return d.getRecord(name, true)
+}
+
+// StatResource returns the record of the resource without its content.
+func (d *Dir) StatResource(name string) (Record, error) {
+	return d.getRecord(name, false)
+}
+
+// ListResources returns names of all resources, sorted by their name.
+func (d *Dir) ListResources() ([]string, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	files, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, file := range files {
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		name, err := ioutil.ReadFile(fp.Join(d.dir, file.Name(), ".name"))
+		if err != nil {
+			continue
+		}
+
+		names = append(names, string(name))
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Close does nothing, since there is nothing to release.
+func (d *Dir) Close() error {
+	return nil
+}
+
+func (d *Dir) getRecord(name string, withContent bool) (Record, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	entryPath := fp.Join(d.dir, entryName(name))
+	files, err := ioutil.ReadDir(entryPath)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := Record{}
+	for _, file := range files {
+		key := file.Name()
+		if key == ".name" || (key == "content" && !withContent) {
+			continue
+		}
+
+		value, err := ioutil.ReadFile(fp.Join(entryPath, key))
+		if err != nil {
+			return nil, err
+		}
+
+		record[key] = value
+	}
+
+	return record, nil
+}
+
+// entryName returns the directory name for the resource. The name is
+// always hashed, since the resource name might not be safe to be used as
+// file name, and the names that only differ in case would be clashed in
+// case-insensitive file system.
+func entryName(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
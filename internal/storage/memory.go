@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+)
+
+// Memory is storage that keeps the resources in memory.
+type Memory struct {
+	sync.RWMutex
+	records map[string]Record
+}
+
+// NewMemory creates a new empty memory storage.
+func NewMemory() *Memory {
+	return &Memory{records: make(map[string]Record)}
+}
+
+// PutResource saves the record for resource with specified name.
+func (m *Memory) PutResource(name string, record Record) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.records[name] = copyRecord(record, true)
+	return nil
+}
+
+// GetResource returns the complete record of the resource.
+func (m *Memory) GetResource(name string) (Record, error) {
+	return m.getRecord(name, true)
+}
+
+// StatResource returns the record of the resource without its content.
+func (m *Memory) StatResource(name string) (Record, error) {
+	return m.getRecord(name, false)
+}
+
+// ListResources returns names of all resources, sorted by their name.
+func (m *Memory) ListResources() ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	names := make([]string, 0, len(m.records))
+	for name := range m.records {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Close does nothing, since there is nothing to release.
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (m *Memory) getRecord(name string, withContent bool) (Record, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	record, exist := m.records[name]
+	if !exist {
+		return nil, ErrNotExist
+	}
+
+	return copyRecord(record, withContent), nil
+}
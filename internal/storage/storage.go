@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"errors"
+)
+
+// ErrNotExist is returned when the requested resource doesn't exist.
+var ErrNotExist = errors.New("resource doesn't exist")
+
+// Record is the saved fields of a single resource, e.g. its
+// compressed content, content type and HTTP metadata.
+type Record map[string][]byte
+
+// Storage is the container for archive's resources.
+type Storage interface {
+	// PutResource saves the record for resource with specified
+	// name. If the resource already exists, it will be replaced.
+	PutResource(name string, record Record) error
+
+	// GetResource returns the complete record of the resource.
+	GetResource(name string) (Record, error)
+
+	// StatResource returns the record of the resource without its content.
+	StatResource(name string) (Record, error)
+
+	// ListResources returns names of all resources, sorted by their name.
+	ListResources() ([]string, error)
+
+	// Close closes the storage.
+	Close() error
+}
+
+// copyRecord returns a copy of the record. If withContent is false,
+// the content will be excluded from the copy.
+func copyRecord(record Record, withContent bool) Record {
+	newRecord := make(Record, len(record))
+	for key, value := range record {
+		if key == "content" && !withContent {
+			continue
+		}
+
+		newRecord[key] = append([]byte(nil), value...)
+	}
+
+	return newRecord
+}
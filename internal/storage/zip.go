@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	fp "path/filepath"
+	"sort"
+	"strings"
+)
+
+// Zip is storage that saves the resources inside a zip file, where each
+// field of resource is saved as file named "<resource-name>/<field>".
+// Since zip file can't be modified in place, the resources are kept in
+// memory and the zip file is only written when the storage is closed.
+type Zip struct {
+	*Memory
+	path  string
+	dirty bool
+}
+
+// OpenZip opens zip file in specified path as storage. If the
+// file doesn't exist yet, it will be created once closed.
+func OpenZip(path string) (*Zip, error) {
+	storage := &Zip{
+		Memory: NewMemory(),
+		path:   path,
+	}
+
+	zipReader, err := zip.OpenReader(path)
+	if os.IsNotExist(err) {
+		return storage, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer zipReader.Close()
+
+	// Load all records from zip file
+	for _, file := range zipReader.File {
+		parts := strings.SplitN(file.Name, "/", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+
+		fileReader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := ioutil.ReadAll(fileReader)
+		fileReader.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		name, key := parts[0], parts[1]
+		record, exist := storage.records[name]
+		if !exist {
+			record = Record{}
+			storage.records[name] = record
+		}
+
+		record[key] = value
+	}
+
+	return storage, nil
+}
+
+// PutResource saves the record for resource with specified name.
+func (z *Zip) PutResource(name string, record Record) error {
+	z.Lock()
+	z.dirty = true
+	z.Unlock()
+
+	return z.Memory.PutResource(name, record)
+}
+
+// Close writes the resources into zip file, if there are any changes.
+// The zip is written into temporary file first, then moved to its place.
+func (z *Zip) Close() error {
+	z.RLock()
+	defer z.RUnlock()
+
+	if !z.dirty {
+		return nil
+	}
+
+	os.MkdirAll(fp.Dir(z.path), os.ModePerm)
+	tmpFile, err := ioutil.TempFile(fp.Dir(z.path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	err = z.writeZip(tmpFile)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), z.path)
+}
+
+func (z *Zip) writeZip(file *os.File) error {
+	names := make([]string, 0, len(z.records))
+	for name := range z.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zipWriter := zip.NewWriter(file)
+	for _, name := range names {
+		record := z.records[name]
+		keys := make([]string, 0, len(record))
+		for key := range record {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			// Content is already compressed, so just store it
+			header := &zip.FileHeader{
+				Name:   name + "/" + key,
+				Method: zip.Deflate,
+			}
+
+			if key == "content" {
+				header.Method = zip.Store
+			}
+
+			fileWriter, err := zipWriter.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+
+			_, err = fileWriter.Write(record[key])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return zipWriter.Close()
+}
@@ -0,0 +1,125 @@
+package warcfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reader reads WARC records from the underlying reader.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a new WARC reader. The input may be either plain
+// WARC file or gzipped WARC file, which usually compressed per record
+// as its own gzip member.
+func NewReader(r io.Reader) (*Reader, error) {
+	bufReader := bufio.NewReader(r)
+
+	// Check if input is compressed using gzip magic number
+	magic, err := bufReader.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(bufReader)
+		if err != nil {
+			return nil, err
+		}
+
+		bufReader = bufio.NewReader(gzipReader)
+	}
+
+	return &Reader{r: bufReader}, nil
+}
+
+// ReadRecord reads the next record. Returns io.EOF
+// when there are no more records to read.
+func (r *Reader) ReadRecord() (*Record, error) {
+	// Find the version line, skipping the blank lines
+	// that separate the records.
+	var line string
+	for {
+		var err error
+		line, err = r.readLine()
+		if err != nil {
+			if err == io.EOF && line == "" {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+
+		if line != "" {
+			break
+		}
+	}
+
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("invalid version line %q", line)
+	}
+
+	// Read header fields until empty line
+	record := &Record{}
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %v", err)
+		}
+
+		if line == "" {
+			break
+		}
+
+		// Handle folded header
+		if (line[0] == ' ' || line[0] == '\t') && len(record.Fields) > 0 {
+			last := &record.Fields[len(record.Fields)-1]
+			last.Value += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid header line %q", line)
+		}
+
+		record.Fields = append(record.Fields, Field{
+			Name:  strings.TrimSpace(parts[0]),
+			Value: strings.TrimSpace(parts[1]),
+		})
+	}
+
+	// Read content block
+	length, err := strconv.ParseInt(record.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid content length %q", record.Get("Content-Length"))
+	}
+
+	// The length can't be trusted, so the buffer only grows as the
+	// content is actually read, instead of allocated up front.
+	buffer := bytes.NewBuffer(nil)
+	n, err := io.CopyN(buffer, r.r, length)
+	if err == io.EOF {
+		err = fmt.Errorf("content is truncated after %d of %d bytes", n, length)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content: %v", err)
+	}
+
+	record.Content = buffer.Bytes()
+
+	return record, nil
+}
+
+// readLine reads a single line without its line ending.
+func (r *Reader) readLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	return line, err
+}
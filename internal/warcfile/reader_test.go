@@ -0,0 +1,70 @@
+package warcfile
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadRecord(t *testing.T) {
+	input := "WARC/1.1\r\n" +
+		"WARC-Type: resource\r\n" +
+		"Content-Length: 5\r\n" +
+		"\r\n" +
+		"hello\r\n\r\n"
+
+	r, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+
+	record, err := r.ReadRecord()
+	if err != nil {
+		t.Fatalf("failed to read record: %v", err)
+	}
+
+	if got := record.Get("WARC-Type"); got != "resource" {
+		t.Errorf("WARC-Type = %q, want %q", got, "resource")
+	}
+
+	if got := string(record.Content); got != "hello" {
+		t.Errorf("content = %q, want %q", got, "hello")
+	}
+
+	if _, err = r.ReadRecord(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last record, got %v", err)
+	}
+}
+
+func TestReadRecordInvalidLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		length string
+		body   string
+	}{
+		{"truncated", "100", "short"},
+		{"oversized", "9223372036854775807", "short"},
+		{"negative", "-1", "short"},
+		{"not a number", "abc", "short"},
+		{"missing", "", "short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "WARC/1.1\r\n" +
+				"WARC-Type: resource\r\n" +
+				"Content-Length: " + tt.length + "\r\n" +
+				"\r\n" +
+				tt.body
+
+			r, err := NewReader(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("failed to create reader: %v", err)
+			}
+
+			if _, err = r.ReadRecord(); err == nil || err == io.EOF {
+				t.Errorf("expected error for content length %q, got %v", tt.length, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package warcfile
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Version is the WARC version that written by this package.
+const Version = "WARC/1.1"
+
+// Field is a single named field in WARC record header.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Record is a single WARC record, which consists of
+// named header fields and a content block.
+type Record struct {
+	Fields  []Field
+	Content []byte
+}
+
+// Get returns value of the first header field with specified name.
+// Field name is case insensitive, as defined by the WARC spec.
+func (r *Record) Get(name string) string {
+	for _, field := range r.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+
+	return ""
+}
+
+// Set sets value of header field with specified name. If the
+// field doesn't exist yet, it will be appended to the header.
+func (r *Record) Set(name, value string) {
+	for i, field := range r.Fields {
+		if strings.EqualFold(field.Name, name) {
+			r.Fields[i].Value = value
+			return
+		}
+	}
+
+	r.Fields = append(r.Fields, Field{Name: name, Value: value})
+}
+
+// Type returns the WARC-Type of the record.
+func (r *Record) Type() string {
+	return r.Get("WARC-Type")
+}
+
+// NewRecordID generates a new unique URI for WARC-Record-ID.
+func NewRecordID() string {
+	uuid := make([]byte, 16)
+	rand.Read(uuid)
+
+	// Mark it as version 4 (random) UUID
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>",
+		uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+// FormatDate formats time as W3C-ISO8601 date used in WARC-Date.
+func FormatDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// Digest returns the labelled SHA-1 digest of the data, which
+// used in WARC-Block-Digest and WARC-Payload-Digest.
+func Digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
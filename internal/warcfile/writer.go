@@ -0,0 +1,55 @@
+package warcfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strconv"
+)
+
+// Writer writes WARC records into the underlying writer.
+type Writer struct {
+	w        io.Writer
+	compress bool
+}
+
+// NewWriter returns a new WARC writer. If compress is true, each
+// record will be written as its own gzip member, so the output is
+// a valid .warc.gz file that can be accessed randomly per record.
+func NewWriter(w io.Writer, compress bool) *Writer {
+	return &Writer{
+		w:        w,
+		compress: compress,
+	}
+}
+
+// WriteRecord writes the record. Content-Length is always
+// recalculated from the record's content.
+func (w *Writer) WriteRecord(record *Record) error {
+	record.Set("Content-Length", strconv.Itoa(len(record.Content)))
+
+	// Build the record
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString(Version + "\r\n")
+	for _, field := range record.Fields {
+		buffer.WriteString(field.Name + ": " + field.Value + "\r\n")
+	}
+	buffer.WriteString("\r\n")
+	buffer.Write(record.Content)
+	buffer.WriteString("\r\n\r\n")
+
+	// Write it as it is if compression not needed
+	if !w.compress {
+		_, err := w.w.Write(buffer.Bytes())
+		return err
+	}
+
+	// Otherwise write it as a new gzip member
+	gzipper := gzip.NewWriter(w.w)
+	_, err := gzipper.Write(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return gzipper.Close()
+}
@@ -0,0 +1,97 @@
+package warc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-shiori/warc/internal/archiver"
+)
+
+// Version is the version of this library.
+const Version = archiver.Version
+
+// FormatVersion is the newest format version of the archive.
+const FormatVersion = archiver.FormatVersion
+
+// Manifest is the metadata of the archived page, which saved
+// by archiver once the archival is finished. Failures lists the
+// sub resources that failed to be downloaded.
+type Manifest struct {
+	URL           string
+	FinalURL      string
+	Title         string
+	UserAgent     string
+	Version       string
+	FormatVersion int
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	ResourceCount int
+	TotalSize     int64
+	Failures      []Failure
+}
+
+// Manifest returns the manifest of the archive. Archives
+// created by older version of this package don't have manifest.
+func (arc *Archive) Manifest() (Manifest, error) {
+	record, err := arc.storage.GetResource(archiver.ManifestBucket)
+	if err == ErrNotExist {
+		return Manifest{}, fmt.Errorf("archive doesn't have manifest")
+	}
+
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read manifest: %v", err)
+	}
+
+	get := func(key string) string {
+		return string(record[key])
+	}
+
+	manifest := Manifest{
+		URL:       get("url"),
+		FinalURL:  get("final-url"),
+		Title:     get("title"),
+		UserAgent: get("user-agent"),
+		Version:   get("version"),
+	}
+
+	manifest.StartedAt, _ = time.Parse(time.RFC3339Nano, get("start-time"))
+	manifest.FinishedAt, _ = time.Parse(time.RFC3339Nano, get("end-time"))
+	manifest.FormatVersion, _ = strconv.Atoi(get("format-version"))
+	manifest.ResourceCount, _ = strconv.Atoi(get("resource-count"))
+	manifest.TotalSize, _ = strconv.ParseInt(get("total-size"), 10, 64)
+
+	if failures := record["failures"]; len(failures) > 0 {
+		err = json.Unmarshal(failures, &manifest.Failures)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("invalid failures in manifest: %v", err)
+		}
+	}
+	return manifest, nil
+}
+
+// readFormatVersion reads the format version from archive's manifest.
+// If the manifest doesn't exist, it's a legacy archive i.e. version 0.
+func readFormatVersion(s Storage) (int, error) {
+	record, err := s.StatResource(archiver.ManifestBucket)
+	if err == ErrNotExist {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to read manifest: %v", err)
+	}
+
+	strVersion := record["format-version"]
+	if strVersion == nil {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(string(strVersion))
+	if err != nil {
+		return 0, fmt.Errorf("invalid format version %q", strVersion)
+	}
+
+	return version, nil
+}
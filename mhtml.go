@@ -0,0 +1,219 @@
+package warc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// ExportMHTML writes the archive into w as MHTML file, i.e. a MIME message
+// with multipart/related content which can be opened by most browsers.
+// Each resource is written as its own part with Content-Location set to its
+// original URL, and the archive root is written as the first part.
+func ExportMHTML(arc *Archive, w io.Writer) error {
+	// Load all resources from archive
+	resources, err := arc.loadResources()
+	if err != nil {
+		return fmt.Errorf("failed to load resources: %v", err)
+	}
+
+	if len(resources) == 0 || resources[0].Name != "archive-root" {
+		return fmt.Errorf("archive-root doesn't exist")
+	}
+
+	// Resources are referenced by their Content-Location, so the resource
+	// names must be reverted to the original URL. Old archives don't save
+	// the original URL, so in that case the name is used as location.
+	urlMap := make(map[string]string)
+	for _, res := range resources {
+		if res.URL != "" {
+			urlMap[res.Name] = res.URL
+		}
+	}
+
+	revertName := func(name string) string {
+		return urlMap[name]
+	}
+
+	// Write message header
+	mw := multipart.NewWriter(w)
+	rootLocation := resources[0].URL
+
+	_, err = fmt.Fprintf(w, "From: <Saved by github.com/go-shiori/warc>\r\n"+
+		"Snapshot-Content-Location: %s\r\n"+
+		"Date: %s\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: multipart/related;\r\n"+
+		"\ttype=\"text/html\";\r\n"+
+		"\tboundary=\"%s\"\r\n\r\n",
+		rootLocation, time.Now().Format(time.RFC1123Z), mw.Boundary())
+	if err != nil {
+		return err
+	}
+
+	// Write each resource as its own part
+	for _, res := range resources {
+		content, err := revertResource(res, revertName)
+		if err != nil {
+			return err
+		}
+
+		location := res.URL
+		if location == "" {
+			location = res.Name
+		}
+
+		// Text is encoded using quoted-printable so it's still readable,
+		// while the other is encoded using base64.
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", res.ContentType)
+		header.Set("Content-Location", location)
+
+		if strings.HasPrefix(res.ContentType, "text/") {
+			header.Set("Content-Transfer-Encoding", "quoted-printable")
+		} else {
+			header.Set("Content-Transfer-Encoding", "base64")
+		}
+
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", res.Name, err)
+		}
+
+		if strings.HasPrefix(res.ContentType, "text/") {
+			qpWriter := quotedprintable.NewWriter(part)
+			_, err = qpWriter.Write(content)
+			if err == nil {
+				err = qpWriter.Close()
+			}
+		} else {
+			err = writeBase64Lines(part, content)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", res.Name, err)
+		}
+	}
+
+	return mw.Close()
+}
+
+// ImportMHTML reads the parts inside an MHTML file, then archives the root
+// part into dstPath. Just like ImportWARC, the parts are processed like a
+// page that downloaded from internet, so the resource names will be
+// identical with the ones created by NewArchive. The root part is the one
+// specified by the `start` parameter, or the first part if not specified.
+func ImportMHTML(r io.Reader, dstPath string) error {
+	// Read all parts from MHTML
+	transport, err := readMHTMLCaptures(r)
+	if err != nil {
+		return fmt.Errorf("failed to read MHTML: %v", err)
+	}
+
+	if transport.rootURL == "" {
+		return fmt.Errorf("MHTML doesn't have root part with valid location")
+	}
+
+	// Archive the root part using the other parts as the source
+	req := ArchivalRequest{
+		URL:         transport.rootURL,
+		HTTPClient:  &http.Client{Transport: transport},
+		RetryPolicy: RetryPolicy{MaxAttempts: 1},
+		replay:      true,
+	}
+
+	_, err = NewArchive(req, dstPath)
+	return err
+}
+
+// readMHTMLCaptures reads every parts inside MHTML file
+// as captures that can be used for archival.
+func readMHTMLCaptures(r io.Reader) (*captureTransport, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("content type %s is not multipart", mediaType)
+	}
+
+	start := strings.Trim(params["start"], "<>")
+	transport := newCaptureTransport()
+	partReader := multipart.NewReader(msg.Body, params["boundary"])
+
+	for i := 0; ; i++ {
+		part, err := partReader.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Quoted-printable is already decoded by multipart reader,
+		// so here only base64 needs to be decoded.
+		var reader io.Reader = part
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			reader = base64.NewDecoder(base64.StdEncoding, part)
+		}
+
+		content, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		location := strings.TrimSpace(part.Header.Get("Content-Location"))
+		if location == "" {
+			continue
+		}
+
+		// Check if this is the root part
+		contentID := strings.Trim(part.Header.Get("Content-ID"), "<>")
+		if (start == "" && i == 0) || (start != "" && start == contentID) {
+			transport.rootURL = location
+		}
+
+		transport.addCapture(location, capture{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {part.Header.Get("Content-Type")}},
+			Content:    content,
+		})
+	}
+
+	return transport, nil
+}
+
+// writeBase64Lines writes content as base64, which split
+// into lines of 76 characters as required by RFC 2045.
+func writeBase64Lines(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for start := 0; start < len(encoded); start += 76 {
+		end := start + 76
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+
+		_, err := io.WriteString(w, encoded[start:end]+"\r\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
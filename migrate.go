@@ -0,0 +1,168 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-shiori/warc/internal/archiver"
+	"github.com/go-shiori/warc/internal/processor"
+)
+
+// migrations contains functions for upgrading the archive's layout,
+// where migrations[i] upgrades the archive from version i to i+1.
+var migrations = []func(s Storage) error{
+	migrateV0,
+	migrateV1,
+}
+
+// Migrate upgrades the archive in srcPath to the newest format version,
+// then save it to dstPath. The source archive is not modified. If the
+// source archive is already in the newest format, it's copied as it is.
+func Migrate(srcPath, dstPath string) error {
+	// Open source archive
+	src, err := Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Create destination archive
+	_, err = os.Stat(dstPath)
+	if err == nil {
+		return fmt.Errorf("%s already exists", dstPath)
+	}
+
+	dst, err := OpenBoltStorage(dstPath, false)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %v", err)
+	}
+
+	err = migrateStorage(src, dst)
+	dst.Close()
+
+	if err != nil {
+		os.Remove(dstPath)
+		return err
+	}
+
+	return nil
+}
+
+// migrateStorage copies all records from the source archive
+// into dst, then upgrade it one version at a time.
+func migrateStorage(src *Archive, dst Storage) error {
+	names, err := src.storage.ListResources()
+	if err != nil {
+		return fmt.Errorf("failed to copy archive: %v", err)
+	}
+
+	for _, name := range names {
+		record, err := src.storage.GetResource(name)
+		if err != nil {
+			return fmt.Errorf("failed to copy archive: %v", err)
+		}
+
+		err = dst.PutResource(name, record)
+		if err != nil {
+			return fmt.Errorf("failed to copy archive: %v", err)
+		}
+	}
+
+	for version := src.formatVersion; version < FormatVersion; version++ {
+		if err := migrations[version](dst); err != nil {
+			return fmt.Errorf("failed to migrate from version %d: %v", version, err)
+		}
+	}
+
+	// Mark the new format version
+	manifest, err := dst.GetResource(archiver.ManifestBucket)
+	if err == ErrNotExist {
+		manifest, err = Record{}, nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	manifest["format-version"] = []byte(strconv.Itoa(FormatVersion))
+	return dst.PutResource(archiver.ManifestBucket, manifest)
+}
+
+// migrateV0 upgrades the legacy archive which only has `content` and
+// `type` for each resource. It adds the uncompressed size for each
+// resource, then creates the manifest from the available data.
+func migrateV0(s Storage) error {
+	names, err := s.ListResources()
+	if err != nil {
+		return err
+	}
+
+	// Save uncompressed size for each resource
+	var title string
+	var resourceCount int
+	var totalSize int64
+
+	for _, name := range names {
+		record, err := s.GetResource(name)
+		if err != nil {
+			return err
+		}
+
+		content := record["content"]
+		contentType := record["type"]
+		if content == nil || contentType == nil {
+			continue
+		}
+
+		gzipReader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %v", name, err)
+		}
+
+		// Only the root's content is needed, for extracting the title
+		var decompressed bytes.Buffer
+		var dst io.Writer = ioutil.Discard
+		if name == "archive-root" && strings.Contains(string(contentType), "text/html") {
+			dst = &decompressed
+		}
+
+		size, err := io.Copy(dst, gzipReader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %v", name, err)
+		}
+
+		if decompressed.Len() > 0 {
+			title, _ = processor.ExtractTitle(&decompressed)
+		}
+
+		record["size"] = []byte(strconv.FormatInt(size, 10))
+		record["compressed-size"] = []byte(strconv.Itoa(len(content)))
+		err = s.PutResource(name, record)
+		if err != nil {
+			return err
+		}
+
+		resourceCount++
+		totalSize += size
+	}
+
+	// Create the manifest
+	return s.PutResource(archiver.ManifestBucket, Record{
+		"title":          []byte(title),
+		"version":        []byte(Version),
+		"resource-count": []byte(strconv.Itoa(resourceCount)),
+		"total-size":     []byte(strconv.FormatInt(totalSize, 10)),
+	})
+}
+
+// migrateV1 upgrades archive from version 1. Version 2 only allows the
+// content to be saved as reference to blob store, so nothing to change.
+func migrateV1(s Storage) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package warc
+
+import (
+	"github.com/go-shiori/warc/internal/processor"
+)
+
+// ScriptPolicy decides how the scripts inside HTML are archived.
+type ScriptPolicy = processor.ScriptPolicy
+
+const (
+	// ScriptRemove removes all scripts from the HTML. This is the default
+	// policy, since the scripts might load data that not archived.
+	ScriptRemove = processor.ScriptRemove
+
+	// ScriptKeep keeps the scripts, with the resource URLs
+	// inside them rewritten to the archived resources.
+	ScriptKeep = processor.ScriptKeep
+
+	// ScriptSandbox is like ScriptKeep, but the network APIs like fetch
+	// and XMLHttpRequest are disabled so the scripts can't fetch any data.
+	ScriptSandbox = processor.ScriptSandbox
+)
+
+// IntegrityPolicy decides how the Subresource Integrity attributes inside
+// HTML are handled. Since the archived CSS and JS are rewritten, the
+// original hashes are no longer valid and browsers would refuse them.
+type IntegrityPolicy = processor.IntegrityPolicy
+
+const (
+	// IntegrityStrip removes the integrity and crossorigin attributes.
+	// This is the default policy.
+	IntegrityStrip = processor.IntegrityStrip
+
+	// IntegrityRecompute replaces the integrity attributes with
+	// the hash of the resources as they're saved in the archive.
+	IntegrityRecompute = processor.IntegrityRecompute
+)
@@ -1,15 +1,53 @@
 package warc
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"go.etcd.io/bbolt"
+	"github.com/go-shiori/warc/internal/archiver"
 )
 
 // Archive is the storage for archiving the web page.
 type Archive struct {
-	db *bbolt.DB
+	storage       Storage
+	formatVersion int
+	blobStore     BlobStore
+}
+
+// ResourceInfo is the metadata of an archived resource. Size is the
+// size of the content once decompressed, while CompressedSize is the
+// size of the content as it's stored in the archive. The HTTP
+// metadata is only available for resources that downloaded by
+// archiver, and also not available in archives created by the
+// older version of this package. Attempts is the history of the
+// download attempts, where the last one is the saved response.
+// ContentType is the actual type of the content, which might be
+// sniffed when the server sent a missing or generic type. In that
+// case OriginalContentType is the type that sent by the server.
+type ResourceInfo struct {
+	Name                string
+	URL                 string
+	FinalURL            string
+	ContentType         string
+	OriginalContentType string
+	Size                int64
+	CompressedSize      int64
+	Status              string
+	StatusCode          int
+	Header              http.Header
+	RequestHeader       http.Header
+	FetchedAt           time.Time
+	Attempts            []Attempt
 }
 
 // Open opens the archive from specified path.
@@ -21,58 +59,104 @@ func Open(path string) (*Archive, error) {
 	}
 
 	// Open database
-	options := &bbolt.Options{
-		ReadOnly: true,
+	s, err := OpenBoltStorage(path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	arc, err := OpenStorage(s)
+	if err != nil {
+		s.Close()
+		return nil, err
 	}
 
-	db, err := bbolt.Open(path, os.ModePerm, options)
+	return arc, nil
+}
+
+// OpenStorage opens the archive that saved inside the specified storage.
+// Closing the archive will close the storage as well.
+func OpenStorage(s Storage) (*Archive, error) {
+	// Make sure the archive's format is supported
+	version, err := readFormatVersion(s)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Archive{db: db}, nil
+	if version > FormatVersion {
+		return nil, fmt.Errorf("archive format version %d is not supported", version)
+	}
+
+	return &Archive{storage: s, formatVersion: version}, nil
+}
+
+// FormatVersion returns the format version of the archive. Archives
+// created by older version of this package are version 0, which can
+// be upgraded to the newest format using Migrate.
+func (arc *Archive) FormatVersion() int {
+	return arc.formatVersion
 }
 
 // Close closes the storage.
 func (arc *Archive) Close() {
-	arc.db.Close()
+	arc.storage.Close()
+}
+
+// ResourceReader reads the decompressed content of an archived resource.
+// It must be closed after use to release the underlying decompressor.
+type ResourceReader struct {
+	io.Reader
+	Size        int64
+	ContentType string
+
+	closer io.Closer
+}
+
+// Close closes the reader.
+func (r *ResourceReader) Close() error {
+	return r.closer.Close()
 }
 
 // Read fetch the resource with specified name from archive.
+// The content is returned as it's stored in archive, i.e. compressed
+// using gzip, so it can be served directly with `Content-Encoding: gzip`.
+// Use Open to read the decompressed content.
 func (arc *Archive) Read(name string) ([]byte, string, error) {
 	// Make sure name exists
 	if name == "" {
 		name = "archive-root"
 	}
 
-	var content []byte
-	var strContentType string
+	record, err := arc.getResource(name)
+	if err != nil {
+		return nil, "", err
+	}
 
-	err := arc.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte(name))
-		if bucket == nil {
-			return fmt.Errorf("%s doesn't exist", name)
-		}
+	content, err := arc.resourceContent(name, record)
+	if err != nil {
+		return nil, "", err
+	}
 
-		contentType := bucket.Get([]byte("type"))
-		if contentType == nil {
-			return fmt.Errorf("%s doesn't exist", name)
-		}
-		strContentType = string(contentType)
+	return content, string(record["type"]), nil
+}
 
-		content = bucket.Get([]byte("content"))
-		if content == nil {
-			return fmt.Errorf("%s doesn't exist", name)
-		}
+// getResource returns the record of resource with specified name.
+// The manifest and records without content type are not resource,
+// so they are treated as not exist.
+func (arc *Archive) getResource(name string) (Record, error) {
+	if name == archiver.ManifestBucket {
+		return nil, fmt.Errorf("%s doesn't exist", name)
+	}
 
-		return nil
-	})
+	record, err := arc.storage.GetResource(name)
+	if err == ErrNotExist || err == nil && record["type"] == nil {
+		return nil, fmt.Errorf("%s doesn't exist", name)
+	}
 
 	if err != nil {
-		return nil, "", err
+		return nil, fmt.Errorf("failed to read %s: %v", name, err)
 	}
 
-	return content, strContentType, nil
+	return record, nil
 }
 
 // HasResource checks if the resource exists in archive.
@@ -82,12 +166,199 @@ func (arc *Archive) HasResource(name string) bool {
 		name = "archive-root"
 	}
 
-	var exists bool
-	arc.db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte(name))
-		exists = bucket != nil
+	if name == archiver.ManifestBucket {
+		return false
+	}
+
+	_, err := arc.storage.StatResource(name)
+	return err == nil
+}
+
+// Stat returns the metadata of the resource with specified name.
+func (arc *Archive) Stat(name string) (ResourceInfo, error) {
+	// Make sure name exists
+	if name == "" {
+		name = "archive-root"
+	}
+
+	if name == archiver.ManifestBucket {
+		return ResourceInfo{}, fmt.Errorf("%s doesn't exist", name)
+	}
+
+	record, err := arc.statResource(name)
+	if err == ErrNotExist {
+		return ResourceInfo{}, fmt.Errorf("%s doesn't exist", name)
+	}
+
+	if err != nil {
+		return ResourceInfo{}, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+
+	return resourceInfo(name, record), nil
+}
+
+// List returns the metadata of all resources inside archive,
+// sorted by their name.
+func (arc *Archive) List() ([]ResourceInfo, error) {
+	infos := []ResourceInfo{}
+	err := arc.Walk(func(info ResourceInfo) error {
+		infos = append(infos, info)
 		return nil
 	})
 
-	return exists
+	if err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+// Walk calls fn for the metadata of each resource inside archive, in
+// order of their name. If fn returns an error, the walk will be stopped
+// and the error will be returned by Walk. Since fn is called after the
+// metadata are read, it's safe to read the archive from inside fn.
+func (arc *Archive) Walk(fn func(ResourceInfo) error) error {
+	names, err := arc.storage.ListResources()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if name == archiver.ManifestBucket {
+			continue
+		}
+
+		record, err := arc.statResource(name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", name, err)
+		}
+
+		if err := fn(resourceInfo(name, record)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// statResource returns the record of resource without its content.
+// Older archives don't save the sizes of resource, so in that case
+// the complete record is loaded for calculating the sizes.
+func (arc *Archive) statResource(name string) (Record, error) {
+	record, err := arc.storage.StatResource(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if record["size"] != nil && record["compressed-size"] != nil {
+		return record, nil
+	}
+
+	return arc.storage.GetResource(name)
+}
+
+// resourceInfo reads the resource metadata from its record.
+func resourceInfo(name string, record Record) ResourceInfo {
+	content := record["content"]
+	compressedSize := int64(len(content))
+	if strSize := record["compressed-size"]; strSize != nil {
+		compressedSize, _ = strconv.ParseInt(string(strSize), 10, 64)
+	}
+
+	info := ResourceInfo{
+		Name:                name,
+		URL:                 string(record["url"]),
+		FinalURL:            string(record["final-url"]),
+		ContentType:         string(record["type"]),
+		OriginalContentType: string(record["original-type"]),
+		Size:                uncompressedSize(record),
+		CompressedSize:      compressedSize,
+		Status:              string(record["status"]),
+		Header:              decodeHeader(record["header"]),
+		RequestHeader:       decodeHeader(record["request-header"]),
+		Attempts:            decodeAttempts(record["attempts"]),
+	}
+
+	// Status is saved as status line, e.g. "HTTP/1.1 200 OK"
+	if parts := strings.SplitN(info.Status, " ", 3); len(parts) >= 2 {
+		info.StatusCode, _ = strconv.Atoi(parts[1])
+	}
+
+	if strTime := record["time"]; strTime != nil {
+		info.FetchedAt, _ = time.Parse(time.RFC3339Nano, string(strTime))
+	}
+
+	return info
+}
+
+// decodeHeader decodes HTTP header from its wire format.
+func decodeHeader(data []byte) http.Header {
+	if len(data) == 0 {
+		return nil
+	}
+
+	data = append(data[:len(data):len(data)], "\r\n"...)
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	header, err := reader.ReadMIMEHeader()
+	if err != nil {
+		return nil
+	}
+
+	return http.Header(header)
+}
+
+// Open opens the resource with specified name for reading. Only the
+// decompression is streamed: the compressed content is still loaded from
+// storage as a whole, but it's decompressed on the fly while it's read,
+// so the decompressed resource doesn't need to be kept in memory.
+func (arc *Archive) Open(name string) (*ResourceReader, error) {
+	// Make sure name exists
+	if name == "" {
+		name = "archive-root"
+	}
+
+	record, err := arc.getResource(name)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := arc.resourceContent(name, record)
+	if err != nil {
+		return nil, err
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %v", name, err)
+	}
+
+	if record["size"] == nil {
+		record["content"] = content
+	}
+
+	return &ResourceReader{
+		Reader:      gzipReader,
+		Size:        uncompressedSize(record),
+		ContentType: string(record["type"]),
+		closer:      gzipReader,
+	}, nil
+}
+
+// uncompressedSize returns the size of resource after decompressed.
+// Archives created by older version don't save the size, so in that
+// case it's taken from the gzip trailer, which is the size modulo 2^32.
+func uncompressedSize(record Record) int64 {
+	if strSize := record["size"]; strSize != nil {
+		size, err := strconv.ParseInt(string(strSize), 10, 64)
+		if err == nil {
+			return size
+		}
+	}
+
+	content := record["content"]
+	if len(content) < 4 {
+		return 0
+	}
+
+	return int64(binary.LittleEndian.Uint32(content[len(content)-4:]))
 }
@@ -0,0 +1,17 @@
+package warc
+
+import (
+	"github.com/go-shiori/warc/internal/archiver"
+)
+
+// Report is the result of the archival, which lists every resource
+// that attempted to be archived. Use Succeeded and Failed to get the
+// resources that archived successfully and the ones that failed.
+type Report = archiver.Report
+
+// ResourceReport is the result of archiving a single resource. Referrer
+// is the URL of resource that refers to it, which is empty for the root.
+// Processor is the processor used for the resource, i.e. "html", "css",
+// "js" or "general". Size is the size of the saved content. If the
+// resource failed to be archived, Err contains the reason.
+type ResourceReport = archiver.ResourceReport
@@ -0,0 +1,58 @@
+package warc
+
+import (
+	"encoding/json"
+
+	"github.com/go-shiori/warc/internal/archiver"
+)
+
+// RetryPolicy specifies how the failed downloads are retried. The delay
+// before each retry is doubled from MinBackoff up to MaxBackoff, with
+// random jitter so the retries to the same server are spread out.
+//
+// A download is retried if the server responds with one of RetryStatus,
+// or if the request failed and RetryError returns true for the error. If
+// RetryError is nil, all errors are retried except when archival is
+// cancelled. Set MaxAttempts to 1 to disable the retry.
+type RetryPolicy = archiver.RetryPolicy
+
+// Attempt is the result of a single attempt for downloading a resource.
+type Attempt = archiver.Attempt
+
+// Failure is a resource that failed to be archived. If the server
+// responds with status code that not accepted, the resource is
+// skipped and StatusCode is the code of that response.
+type Failure = archiver.Failure
+
+// DefaultRetryPolicy is the retry policy that used when the MaxAttempts
+// of the requested policy is zero. It can be changed to set the default
+// for all archivals. Its RetryError only retries the temporary errors,
+// e.g. timeouts, while the others like DNS and certificate errors fail
+// right away.
+var DefaultRetryPolicy = archiver.DefaultRetryPolicy()
+
+// retryPolicy returns the specified policy, or a copy of
+// DefaultRetryPolicy if the policy is not specified.
+func retryPolicy(policy RetryPolicy) RetryPolicy {
+	if policy.MaxAttempts > 0 {
+		return policy
+	}
+
+	policy = DefaultRetryPolicy
+	policy.RetryStatus = append([]int(nil), policy.RetryStatus...)
+	return policy
+}
+
+// decodeAttempts decodes the attempt history saved in archive.
+func decodeAttempts(data []byte) []Attempt {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var attempts []Attempt
+	if err := json.Unmarshal(data, &attempts); err != nil {
+		return nil
+	}
+
+	return attempts
+}
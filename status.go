@@ -0,0 +1,16 @@
+package warc
+
+import (
+	"github.com/go-shiori/warc/internal/archiver"
+)
+
+// RootError is returned when the root page failed to be archived, e.g.
+// because it can't be downloaded or the server responds with a status
+// code that not accepted. StatusCode is zero if the response is not
+// received at all.
+type RootError = archiver.RootError
+
+// DefaultAcceptStatus accepts the successful responses, i.e. 2xx.
+func DefaultAcceptStatus(statusCode int) bool {
+	return archiver.DefaultAcceptStatus(statusCode)
+}
@@ -0,0 +1,56 @@
+package warc
+
+import (
+	"github.com/go-shiori/warc/internal/storage"
+)
+
+// Storage is the container where the archive's resources are saved.
+// Besides the default bolt database, there are storages that save the
+// resources in memory, in a plain directory or in a zip file, which
+// can be used with NewArchiveToStorage and OpenStorage.
+type Storage = storage.Storage
+
+// Record is the saved fields of a single resource inside Storage.
+type Record = storage.Record
+
+// ErrNotExist is returned by Storage when the resource doesn't exist.
+var ErrNotExist = storage.ErrNotExist
+
+// NewMemoryStorage creates storage that keeps the resources in memory.
+func NewMemoryStorage() Storage {
+	return storage.NewMemory()
+}
+
+// OpenBoltStorage opens the bolt database in specified path as storage.
+// This is the storage that used by NewArchive and Open.
+func OpenBoltStorage(path string, readOnly bool) (Storage, error) {
+	s, err := storage.OpenBolt(path, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// OpenDirStorage opens directory as storage, where each resource
+// is saved as its own subdirectory. The directory will be created
+// if it doesn't exist yet.
+func OpenDirStorage(dir string) (Storage, error) {
+	s, err := storage.OpenDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// OpenZipStorage opens zip file as storage. The resources are loaded
+// into memory, and only written back to the file when it's closed.
+func OpenZipStorage(path string) (Storage, error) {
+	s, err := storage.OpenZip(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,251 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-shiori/warc/internal/processor"
+	"github.com/go-shiori/warc/internal/warcfile"
+)
+
+// exportedResource is resource that loaded from archive for export.
+type exportedResource struct {
+	ResourceInfo
+	Content []byte
+}
+
+// ExportWARC writes the archive into w as ISO 28500 WARC 1.1 file.
+// Each record is written as its own gzip member, so the output is a
+// valid .warc.gz file that can be replayed by other web archiving tools.
+// Resource names inside the archived HTML, CSS and JS are reverted back to
+// their original URL, so they can be resolved by the replay tools.
+func ExportWARC(arc *Archive, w io.Writer) error {
+	// Load all resources from archive
+	resources, err := arc.loadResources()
+	if err != nil {
+		return fmt.Errorf("failed to load resources: %v", err)
+	}
+
+	urlMap := make(map[string]string)
+	for _, res := range resources {
+		if res.URL != "" {
+			urlMap[res.Name] = res.URL
+		}
+	}
+
+	revertName := func(name string) string {
+		return urlMap[name]
+	}
+
+	// Use the archival time as capture time
+	captureDate := warcfile.FormatDate(time.Now())
+	if manifest, err := arc.Manifest(); err == nil && !manifest.StartedAt.IsZero() {
+		captureDate = warcfile.FormatDate(manifest.StartedAt)
+	}
+
+	// Write warcinfo record
+	writer := warcfile.NewWriter(w, true)
+	warcinfoID := warcfile.NewRecordID()
+	warcinfo := &warcfile.Record{
+		Fields: []warcfile.Field{
+			{Name: "WARC-Type", Value: "warcinfo"},
+			{Name: "WARC-Record-ID", Value: warcinfoID},
+			{Name: "WARC-Date", Value: warcfile.FormatDate(time.Now())},
+			{Name: "Content-Type", Value: "application/warc-fields"},
+		},
+		Content: []byte("software: github.com/go-shiori/warc\r\n" +
+			"format: WARC File Format 1.1\r\n" +
+			"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"),
+	}
+
+	err = writer.WriteRecord(warcinfo)
+	if err != nil {
+		return fmt.Errorf("failed to write warcinfo: %v", err)
+	}
+
+	// Write each resource
+	for _, res := range resources {
+		// Revert resource names to the original URL
+		content, err := revertResource(res, revertName)
+		if err != nil {
+			return err
+		}
+
+		// Old archives don't store the original URL, so use the name instead
+		targetURI := res.URL
+		if targetURI == "" {
+			targetURI = "urn:x-warc-resource:" + res.Name
+		}
+
+		recordDate := captureDate
+		if !res.FetchedAt.IsZero() {
+			recordDate = warcfile.FormatDate(res.FetchedAt)
+		}
+
+		// If the HTTP metadata is available, save it as response record.
+		// Otherwise, save it as resource record.
+		recordID := warcfile.NewRecordID()
+		record := &warcfile.Record{
+			Fields: []warcfile.Field{
+				{Name: "WARC-Type", Value: "resource"},
+				{Name: "WARC-Record-ID", Value: recordID},
+				{Name: "WARC-Warcinfo-ID", Value: warcinfoID},
+				{Name: "WARC-Date", Value: recordDate},
+				{Name: "WARC-Target-URI", Value: targetURI},
+				{Name: "Content-Type", Value: res.ContentType},
+			},
+			Content: content,
+		}
+
+		if res.Status != "" && res.Header != nil {
+			record.Set("WARC-Type", "response")
+			record.Set("Content-Type", "application/http; msgtype=response")
+			record.Content = httpResponseBlock(res.Status, res.Header, content)
+		}
+
+		record.Set("WARC-Block-Digest", warcfile.Digest(record.Content))
+		record.Set("WARC-Payload-Digest", warcfile.Digest(content))
+
+		err = writer.WriteRecord(record)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", res.Name, err)
+		}
+
+		// Write metadata that link the record to its resource name
+		metadata := "archive-name: " + res.Name + "\r\n"
+		if res.FinalURL != "" && res.FinalURL != res.URL {
+			metadata += "final-url: " + res.FinalURL + "\r\n"
+		}
+
+		metadataRecord := &warcfile.Record{
+			Fields: []warcfile.Field{
+				{Name: "WARC-Type", Value: "metadata"},
+				{Name: "WARC-Record-ID", Value: warcfile.NewRecordID()},
+				{Name: "WARC-Warcinfo-ID", Value: warcinfoID},
+				{Name: "WARC-Date", Value: recordDate},
+				{Name: "WARC-Target-URI", Value: targetURI},
+				{Name: "WARC-Concurrent-To", Value: recordID},
+				{Name: "Content-Type", Value: "application/warc-fields"},
+			},
+			Content: []byte(metadata),
+		}
+
+		err = writer.WriteRecord(metadataRecord)
+		if err != nil {
+			return fmt.Errorf("failed to write metadata for %s: %v", res.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// revertResource returns content of the resource with all resource names
+// inside it reverted using the specified function. Only HTML, CSS and JS
+// are reverted, since the other resources are not modified on archival.
+func revertResource(res exportedResource, fn processor.RewriteFunc) ([]byte, error) {
+	switch {
+	case strings.Contains(res.ContentType, "text/html"):
+		strHTML, err := processor.RewriteHTMLFile(bytes.NewReader(res.Content), fn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revert %s: %v", res.Name, err)
+		}
+		return []byte(strHTML), nil
+	case strings.Contains(res.ContentType, "text/css"):
+		return []byte(processor.RewriteCSSFile(bytes.NewReader(res.Content), fn)), nil
+	case processor.IsJSContentType(res.ContentType):
+		return []byte(processor.RewriteJSFile(bytes.NewReader(res.Content), fn)), nil
+	default:
+		return res.Content, nil
+	}
+}
+
+// httpResponseBlock builds the HTTP response message for response record.
+// Since the body is already decoded and might be rewritten, the headers
+// related to the message length and encoding are recalculated.
+func httpResponseBlock(status string, header http.Header, body []byte) []byte {
+	header = cloneHeader(header)
+	header.Del("Content-Encoding")
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString(status + "\r\n")
+	header.Write(buffer)
+	buffer.WriteString("\r\n")
+	buffer.Write(body)
+
+	return buffer.Bytes()
+}
+
+// cloneHeader returns a copy of the HTTP header.
+func cloneHeader(header http.Header) http.Header {
+	newHeader := make(http.Header, len(header))
+	for key, values := range header {
+		newHeader[key] = append([]string(nil), values...)
+	}
+
+	return newHeader
+}
+
+// loadResources loads and decompresses all resources inside archive.
+// The archive root is always placed as the first resource.
+func (arc *Archive) loadResources() ([]exportedResource, error) {
+	names, err := arc.storage.ListResources()
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []exportedResource{}
+	for _, name := range names {
+		record, err := arc.storage.GetResource(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+
+		if record["type"] == nil {
+			continue
+		}
+
+		content, err := arc.resourceContent(name, record)
+		if err != nil {
+			return nil, err
+		}
+
+		gzipReader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %v", name, err)
+		}
+
+		decompressed, err := ioutil.ReadAll(gzipReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %v", name, err)
+		}
+
+		resources = append(resources, exportedResource{
+			ResourceInfo: resourceInfo(name, record),
+			Content:      decompressed,
+		})
+	}
+
+	sort.SliceStable(resources, func(i, j int) bool {
+		if resources[i].Name == "archive-root" {
+			return true
+		}
+
+		if resources[j].Name == "archive-root" {
+			return false
+		}
+
+		return resources[i].Name < resources[j].Name
+	})
+
+	return resources, nil
+}
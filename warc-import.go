@@ -0,0 +1,179 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-shiori/warc/internal/warcfile"
+)
+
+// ImportWARC reads the captures inside a WARC file, then archives the page
+// with the specified root URL into dstPath. The captures are processed just
+// like a page that downloaded from internet, so the resource names will be
+// identical with the ones created by NewArchive. If rootURL is empty, it
+// will use the page that marked as archive root by ExportWARC, or the first
+// HTML page inside the WARC file. Both plain and gzipped WARC are supported.
+func ImportWARC(r io.Reader, rootURL string, dstPath string) error {
+	// Read all captures from WARC file
+	transport, err := readWARCCaptures(r)
+	if err != nil {
+		return fmt.Errorf("failed to read WARC: %v", err)
+	}
+
+	// Pick the root page
+	if rootURL == "" {
+		rootURL = transport.rootURL
+	}
+
+	if rootURL == "" {
+		rootURL = transport.firstHTML
+	}
+
+	if rootURL == "" {
+		return fmt.Errorf("WARC doesn't contain any HTML page")
+	}
+
+	// Archive the root page using captures as the source
+	req := ArchivalRequest{
+		URL:         rootURL,
+		HTTPClient:  &http.Client{Transport: transport},
+		RetryPolicy: RetryPolicy{MaxAttempts: 1},
+		replay:      true,
+	}
+
+	_, err = NewArchive(req, dstPath)
+	return err
+}
+
+// readWARCCaptures reads every records inside WARC file and
+// collects the HTTP responses that can be used for archival.
+func readWARCCaptures(r io.Reader) (*captureTransport, error) {
+	warcReader, err := warcfile.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := newCaptureTransport()
+
+	for {
+		record, err := warcReader.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		targetURI := strings.Trim(record.Get("WARC-Target-URI"), "<>")
+		key := captureKey(targetURI)
+		if key == "" {
+			continue
+		}
+
+		var res capture
+		switch record.Type() {
+		case "response":
+			res, err = parseHTTPResponse(record.Content)
+			if err != nil {
+				continue
+			}
+		case "resource":
+			res = capture{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {record.Get("Content-Type")}},
+				Content:    record.Content,
+			}
+		case "revisit":
+			refersTo := strings.Trim(record.Get("WARC-Refers-To-Target-URI"), "<>")
+			if refersTo := captureKey(refersTo); refersTo != "" && refersTo != key {
+				transport.aliases[key] = refersTo
+			}
+			continue
+		case "metadata":
+			if bytes.Contains(record.Content, []byte("archive-name: archive-root")) {
+				transport.rootURL = targetURI
+			}
+			continue
+		default:
+			continue
+		}
+
+		transport.addCapture(targetURI, res)
+	}
+
+	return transport, nil
+}
+
+// parseHTTPResponse parses the HTTP response inside WARC response record.
+// The body is decoded from chunked transfer and content encoding.
+func parseHTTPResponse(block []byte) (capture, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	if err != nil {
+		return capture{}, err
+	}
+	defer resp.Body.Close()
+
+	// Some crawlers store the body already dechunked while keeping the
+	// Transfer-Encoding header, so in that case just use the raw body.
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		idx := bytes.Index(block, []byte("\r\n\r\n"))
+		if idx < 0 {
+			return capture{}, err
+		}
+		body = block[idx+4:]
+	}
+
+	// Decode the content encoding
+	contentEncoding := strings.ToLower(resp.Header.Get("Content-Encoding"))
+	if decoded, err := decodeContent(body, contentEncoding); err == nil {
+		body = decoded
+		resp.Header.Del("Content-Encoding")
+	}
+
+	resp.Header.Del("Content-Length")
+	resp.Header.Del("Transfer-Encoding")
+
+	return capture{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Content:    body,
+	}, nil
+}
+
+// decodeContent decodes body that compressed with the specified encoding.
+func decodeContent(body []byte, encoding string) ([]byte, error) {
+	var reader io.Reader
+	var err error
+
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// Deflate is supposed to be zlib stream, however
+		// some servers send raw deflate stream instead.
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %s", encoding)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(reader)
+}
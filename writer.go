@@ -1,49 +1,196 @@
 package warc
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
 	nurl "net/url"
 	"os"
 	fp "path/filepath"
+	"time"
 
 	"github.com/go-shiori/warc/internal/archiver"
-	"go.etcd.io/bbolt"
 )
 
 // ArchivalRequest is request for archiving a web page,
-// either from URL or from an io.Reader.
+// either from URL or from an io.Reader. If ContentType of the
+// reader is not specified, it will be sniffed from its content.
+//
+// By default, each archival uses its own HTTP client with a fresh cookie
+// jar and one minute timeout, which verifies the server certificates.
+// Use HTTPClient to fully replace that client, or Transport to only
+// replace its transport, e.g. for using proxy or custom CA pool.
+//
+// If BlobStore is specified, the resources which content already exists
+// in the store will be saved as reference instead of the content itself.
+// The archive must be opened using OpenWithBlobStore to read them.
+//
+// MaxConcurrency limits the number of downloads that run at the same time,
+// while MaxHostConcurrency limits it for each host. HostDelay is the minimum
+// delay between requests to the same host. When it's zero, the default
+// limits are used, i.e. DefaultMaxConcurrency, DefaultMaxHostConcurrency
+// and no delay. Servers that respond with 429 or 503 and Retry-After
+// header are always honored by waiting before the next request.
+//
+// The failed downloads are retried following RetryPolicy, which defaults
+// to DefaultRetryPolicy. The attempt history of each downloaded resource
+// is available from ResourceInfo, while the resources that failed to be
+// downloaded are listed in the archive's Manifest.
+//
+// AcceptStatus decides whether the downloaded response is archived, which
+// defaults to DefaultAcceptStatus. If the root page is not accepted, the
+// archival fails with *RootError. Otherwise the rejected sub resources are
+// skipped and listed in the archive's Manifest with their status code.
+//
+// If OnEvent is specified, it will receive the progress of the archival,
+// e.g. for showing progress bar. Since it's called from many goroutines,
+// it must be safe for concurrent use, and it should return quickly since
+// it blocks the archival.
+//
+// ScriptPolicy decides how the scripts inside HTML are archived, which
+// by default are removed. If the scripts are kept, the JS files are
+// processed to archive the resources used by them as well. Since the
+// archived CSS and JS are rewritten, IntegrityPolicy decides whether the
+// integrity attributes are removed or recomputed, which by default are
+// removed.
 type ArchivalRequest struct {
-	URL         string
-	Reader      io.Reader
-	ContentType string
-	UserAgent   string
-	LogEnabled  bool
+	URL                string
+	Reader             io.Reader
+	ContentType        string
+	UserAgent          string
+	LogEnabled         bool
+	HTTPClient         *http.Client
+	Transport          http.RoundTripper
+	BlobStore          BlobStore
+	MaxConcurrency     int
+	MaxHostConcurrency int
+	HostDelay          time.Duration
+	RetryPolicy        RetryPolicy
+	AcceptStatus       func(statusCode int) bool
+	OnEvent            func(Event)
+	ScriptPolicy       ScriptPolicy
+	IntegrityPolicy    IntegrityPolicy
+
+	// replay is set by importers, whose responses come from captures
+	replay bool
 }
 
-// NewArchive creates new archive based on submitted request,
-// then save it to specified path.
-func NewArchive(req ArchivalRequest, dstPath string) error {
+const (
+	// DefaultMaxConcurrency is the default number of
+	// downloads that can run at the same time.
+	DefaultMaxConcurrency = archiver.DefaultMaxConcurrency
+
+	// DefaultMaxHostConcurrency is the default number of downloads
+	// from the same host that can run at the same time.
+	DefaultMaxHostConcurrency = archiver.DefaultMaxHostConcurrency
+)
+
+// NewArchive creates new archive based on submitted request, then save
+// it to specified path. The returned report lists every resource that
+// attempted to be archived, and it's also returned when the archival
+// failed, as long as the archival is started.
+func NewArchive(req ArchivalRequest, dstPath string) (*Report, error) {
+	return NewArchiveContext(context.Background(), req, dstPath)
+}
+
+// NewArchiveContext is like NewArchive, but the archival can be
+// cancelled using the specified context. Once cancelled, all in-flight
+// downloads are aborted and the context's error is returned.
+//
+// The archive is written into a temporary file next to dstPath, which
+// is only moved to dstPath once the archival succeeds. So, if dstPath
+// already exists, it's replaced by the new archive, or left untouched
+// when the archival failed or cancelled.
+func NewArchiveContext(ctx context.Context, req ArchivalRequest, dstPath string) (*Report, error) {
 	// Make sure URL is valid
 	parsedURL, err := nurl.ParseRequestURI(req.URL)
 	if err != nil || parsedURL.Scheme == "" || parsedURL.Hostname() == "" {
-		return fmt.Errorf("url \"%s\" is not valid", req.URL)
+		return nil, fmt.Errorf("url \"%s\" is not valid", req.URL)
+	}
+
+	// Create database for archive in temporary path
+	tmpPath, err := tempArchivePath(dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %v", err)
+	}
+
+	s, err := OpenBoltStorage(tmpPath, false)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to create archive: %v", err)
+	}
+
+	report, err := NewArchiveToStorage(ctx, req, s)
+	if closeErr := s.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close archive: %v", closeErr)
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+		return report, err
 	}
 
-	// Create database for archive
-	os.MkdirAll(fp.Dir(dstPath), os.ModePerm)
+	// Archival finished, so move it to the actual path
+	err = os.Rename(tmpPath, dstPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return report, fmt.Errorf("failed to save archive: %v", err)
+	}
 
-	db, err := bbolt.Open(dstPath, os.ModePerm, nil)
+	return report, nil
+}
+
+// tempArchivePath returns an unused path in the directory of dstPath,
+// which is used for writing the archive before it's finished.
+func tempArchivePath(dstPath string) (string, error) {
+	dir := fp.Dir(dstPath)
+	err := os.MkdirAll(dir, os.ModePerm)
 	if err != nil {
-		return fmt.Errorf("failed to create archive: %v", err)
+		return "", err
+	}
+
+	// The file is removed right away, so it's created by bolt
+	// with the same permission as the normal archive file.
+	f, err := ioutil.TempFile(dir, "."+fp.Base(dstPath)+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	f.Close()
+	os.Remove(f.Name())
+	return f.Name(), nil
+}
+
+// NewArchiveToStorage is like NewArchiveContext, but the archive is saved
+// into the specified storage, which is not closed after archival. Unlike
+// NewArchiveContext, the storage is not cleaned up when archival failed,
+// so in that case it should be discarded by the caller.
+func NewArchiveToStorage(ctx context.Context, req ArchivalRequest, s Storage) (*Report, error) {
+	// Make sure URL is valid
+	parsedURL, err := nurl.ParseRequestURI(req.URL)
+	if err != nil || parsedURL.Scheme == "" || parsedURL.Hostname() == "" {
+		return nil, fmt.Errorf("url \"%s\" is not valid", req.URL)
 	}
-	defer db.Close()
 
 	// Start archival
 	arc := archiver.Archiver{
-		DB:         db,
-		UserAgent:  req.UserAgent,
-		LogEnabled: req.LogEnabled,
+		Storage:            s,
+		UserAgent:          req.UserAgent,
+		LogEnabled:         req.LogEnabled,
+		HTTPClient:         req.HTTPClient,
+		Transport:          req.Transport,
+		BlobStore:          req.BlobStore,
+		MaxConcurrency:     req.MaxConcurrency,
+		MaxHostConcurrency: req.MaxHostConcurrency,
+		HostDelay:          req.HostDelay,
+		RetryPolicy:        retryPolicy(req.RetryPolicy),
+		AcceptStatus:       req.AcceptStatus,
+		OnEvent:            req.OnEvent,
+		ScriptPolicy:       req.ScriptPolicy,
+		IntegrityPolicy:    req.IntegrityPolicy,
+		Replay:             req.replay,
 	}
 
 	arcRequest := archiver.Request{
@@ -52,10 +199,17 @@ func NewArchive(req ArchivalRequest, dstPath string) error {
 		ContentType: req.ContentType,
 	}
 
-	err = arc.Start(arcRequest)
-	if err != nil {
-		return fmt.Errorf("archival failed: %v", err)
+	err = arc.Start(ctx, arcRequest)
+	report := arc.Report()
+	if err == nil {
+		return report, nil
+	}
+
+	// RootError and cancellation are returned as it is,
+	// so they can be checked by the caller.
+	if _, isRootError := err.(*RootError); isRootError || err == ctx.Err() {
+		return report, err
 	}
 
-	return nil
+	return report, fmt.Errorf("archival failed: %v", err)
 }
@@ -0,0 +1,63 @@
+package warc
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+)
+
+// ExportZip writes the archive into w as zip file. Each resource is saved
+// as a file named by its resource name, while the archive root is saved
+// as index.html. Since the archived page refers its resources by their
+// name, the page can be opened directly once the zip is extracted.
+func ExportZip(arc *Archive, w io.Writer) error {
+	resources, err := arc.List()
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %v", err)
+	}
+
+	zipWriter := zip.NewWriter(w)
+	for _, res := range resources {
+		fileName := res.Name
+		if fileName == "archive-root" {
+			fileName = "index.html"
+		}
+
+		header := &zip.FileHeader{
+			Name:   fileName,
+			Method: zip.Deflate,
+		}
+
+		if !res.FetchedAt.IsZero() {
+			header.Modified = res.FetchedAt
+		}
+
+		err = writeZipEntry(arc, zipWriter, header, res.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+// writeZipEntry writes the resource with specified name into zip.
+func writeZipEntry(arc *Archive, zipWriter *zip.Writer, header *zip.FileHeader, name string) error {
+	reader, err := arc.Open(name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	fileWriter, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+
+	_, err = io.Copy(fileWriter, reader)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+
+	return nil
+}